@@ -0,0 +1,252 @@
+// Package client looks up webfingers on remote servers.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+// maxBodySize is the maximum size of the documents read from servers.
+const maxBodySize = 1 << 20
+
+var (
+	// ErrInvalidResource is returned when the host of a resource can't be found.
+	ErrInvalidResource = errors.New("invalid resource")
+	// ErrNotFound is returned when the server doesn't know the resource.
+	ErrNotFound = errors.New("resource not found")
+	// ErrUnexpectedStatus is returned for responses other than 200 and 404.
+	ErrUnexpectedStatus = errors.New("unexpected status")
+	// ErrNoLRDD is returned when the host-meta of a server has no LRDD template.
+	ErrNoLRDD = errors.New("no LRDD template in host-meta")
+	// ErrInsecureRedirect is returned when a server redirects away from HTTPS.
+	ErrInsecureRedirect = errors.New("redirect away from HTTPS")
+)
+
+// Client looks up webfingers on remote servers.
+type Client struct {
+	httpClient *http.Client
+	hostMeta   bool
+}
+
+// New creates a client. By default it uses an HTTP client with a timeout,
+// and falls back to host-meta when servers don't have a webfinger endpoint.
+func New(opts ...Option) *Client {
+	c := &Client{
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		hostMeta:   true,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// Only follow redirects to HTTPS (RFC 7033, section 4)
+	if c.httpClient.CheckRedirect == nil {
+		hc := *c.httpClient
+		hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: %s", ErrInsecureRedirect, req.URL)
+			}
+
+			if len(via) >= 10 { //nolint:gomnd // Same limit as the default policy
+				return errors.New("stopped after 10 redirects") //nolint:goerr113 // Same error as the default policy
+			}
+
+			return nil
+		}
+		c.httpClient = &hc
+	}
+
+	return c
+}
+
+// Host returns the host whose server has the webfinger of the resource,
+// which is an acct: URI, an email address or a URL with a host.
+func Host(resource string) (string, error) {
+	u, err := url.Parse(resource)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidResource, err)
+	}
+
+	switch {
+	case u.Host != "":
+		return u.Host, nil
+	case u.Scheme == "acct" || u.Scheme == "mailto" || u.Scheme == "":
+		// The host is after the last @ of acct:user@host
+		account := u.Opaque
+		if u.Scheme == "" {
+			account = u.Path
+		}
+
+		if i := strings.LastIndex(account, "@"); i >= 0 && i < len(account)-1 {
+			return account[i+1:], nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: no host in %s", ErrInvalidResource, resource)
+}
+
+// Lookup fetches the webfinger of the resource from the server of its
+// host, with only the links of the rels if any are given. Email addresses
+// are looked up as acct: URIs.
+func (c *Client) Lookup(ctx context.Context, resource string, rels ...string) (*webfingers.WebFinger, error) {
+	// Use the same subjects as the server
+	if subject, err := webfingers.ParseSubject(resource); err == nil {
+		resource = subject
+	}
+
+	host, err := Host(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{"resource": {resource}}
+	if len(rels) > 0 {
+		q["rel"] = rels
+	}
+
+	endpoint := (&url.URL{Scheme: "https", Host: host, Path: "/.well-known/webfinger", RawQuery: q.Encode()}).String()
+
+	doc, err := c.fetch(ctx, endpoint, "application/jrd+json")
+	if err != nil && c.hostMeta && missingEndpoint(err) {
+		// Older servers only describe their lookups in their host-meta
+		if lrdd, hmErr := c.lrdd(ctx, host); hmErr == nil {
+			doc, err = c.fetchLRDD(ctx, strings.ReplaceAll(lrdd, "{uri}", url.QueryEscape(resource)))
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+
+	return doc.webFinger(rels), nil
+}
+
+// lrdd returns the LRDD template in the host-meta of the host.
+func (c *Client) lrdd(ctx context.Context, host string) (string, error) {
+	endpoint := (&url.URL{Scheme: "https", Host: host, Path: "/.well-known/host-meta"}).String()
+
+	doc, err := c.fetch(ctx, endpoint, "application/xrd+xml, application/json")
+	if err != nil {
+		return "", err
+	}
+
+	// Prefer templates of JSON documents
+	template := ""
+
+	for _, l := range doc.Links {
+		if l.Rel != "lrdd" || l.Template == "" {
+			continue
+		}
+
+		if template == "" || strings.Contains(l.Type, "json") {
+			template = l.Template
+		}
+	}
+
+	if template == "" {
+		return "", fmt.Errorf("%w of %s", ErrNoLRDD, host)
+	}
+
+	return template, nil
+}
+
+// fetchLRDD fetches the document of a filled in LRDD template, which
+// must be HTTPS like the webfinger endpoint.
+func (c *Client) fetchLRDD(ctx context.Context, endpoint string) (*jrd, error) {
+	if u, err := url.Parse(endpoint); err != nil || u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: LRDD template %s", ErrInsecureRedirect, endpoint)
+	}
+
+	return c.fetch(ctx, endpoint, "application/jrd+json, application/xrd+xml")
+}
+
+// fetch gets and parses the JRD or XRD document at the URL.
+func (c *Client) fetch(ctx context.Context, endpoint, accept string) (*jrd, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", accept)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Pages served by servers without the endpoint aren't documents
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	html := mediaType == "text/html"
+
+	switch {
+	case resp.StatusCode == http.StatusOK && html, resp.StatusCode == http.StatusNotFound:
+		return nil, &statusError{err: ErrNotFound, code: resp.StatusCode, url: endpoint, html: html}
+	case resp.StatusCode != http.StatusOK:
+		return nil, &statusError{err: ErrUnexpectedStatus, code: resp.StatusCode, url: endpoint}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", endpoint, err)
+	}
+
+	return parseDocument(body)
+}
+
+// statusError is a response that didn't have a document.
+type statusError struct {
+	err  error
+	code int
+	url  string
+	html bool
+}
+
+func (e *statusError) Error() string {
+	if e.html {
+		return fmt.Sprintf("%s: %s responded with an HTML page", e.err, e.url)
+	}
+
+	return fmt.Sprintf("%s: %s responded with %d", e.err, e.url, e.code)
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// missingEndpoint reports whether the error means the server may not have
+// a webfinger endpoint at all, and not just the resource. A 404 that isn't
+// an HTML page is the server saying it doesn't know the resource.
+func missingEndpoint(err error) bool {
+	// The server may not listen on HTTPS at all
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var se *statusError
+	if !errors.As(err, &se) {
+		return false
+	}
+
+	switch se.code {
+	case http.StatusMethodNotAllowed, http.StatusGone, http.StatusNotImplemented:
+		return true
+	default:
+		return se.html
+	}
+}
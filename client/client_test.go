@@ -0,0 +1,322 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/client"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+const aliceJRD = `{
+	"subject": "acct:alice@example.com",
+	"aliases": ["https://example.com/@alice"],
+	"properties": {"http://schema.org/name": "Alice", "http://example.com/rel/empty": null},
+	"links": [
+		{"rel": "http://webfinger.net/rel/avatar", "type": "image/png", "href": "https://example.com/alice.png"},
+		{"rel": "self", "href": "https://example.com/users/alice"},
+		{"rel": "http://ostatus.org/schema/1.0/subscribe", "template": "https://example.com/follow?uri={uri}"}
+	]
+}`
+
+// newClient creates a client that connects to the TLS server for any host,
+// which is trusted for example.com.
+func newClient(t *testing.T, srv *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	transport := srv.Client().Transport.(*http.Transport).Clone() //nolint:forcetypeassert // Always a transport
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+
+	return client.New(append([]client.Option{client.WithHTTPClient(&http.Client{Transport: transport})}, opts...)...)
+}
+
+func TestHost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		resource string
+		want     string
+		wantErr  bool
+	}{
+		{"acct:alice@example.com", "example.com", false},
+		{"acct:alice@example.com:8443", "example.com:8443", false},
+		{"alice@example.com", "example.com", false},
+		{"mailto:alice@example.com", "example.com", false},
+		{"https://example.com/users/alice", "example.com", false},
+		{"acct:alice", "", true},
+		{"acct:alice@", "", true},
+		{"urn:isbn:123", "", true},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.resource, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := client.Host(tc.resource)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Host() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("Host() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClient_Lookup(t *testing.T) {
+	t.Parallel()
+
+	var gotQuery string
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/webfinger" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		gotQuery = r.URL.RawQuery
+
+		if r.URL.Query().Get("resource") != "acct:alice@example.com" {
+			http.Error(w, "Resource not found", http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/jrd+json")
+		fmt.Fprint(w, aliceJRD)
+	}))
+	defer srv.Close()
+
+	c := newClient(t, srv)
+
+	// Email addresses are looked up as acct: URIs
+	got, err := c.Lookup(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+
+	want := &webfingers.WebFinger{
+		Subject: "acct:alice@example.com",
+		Properties: map[string]string{
+			"http://schema.org/name":       "Alice",
+			"http://example.com/rel/empty": "",
+		},
+		Links: []webfingers.Link{
+			{Rel: "http://webfinger.net/rel/avatar", Href: "https://example.com/alice.png"},
+			{Rel: "self", Href: "https://example.com/users/alice"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup() = %+v, want %+v", got, want)
+	}
+
+	// Rels are sent to the server, and applied if it ignores them
+	got, err = c.Lookup(context.Background(), "acct:alice@example.com", "self")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+
+	if gotQuery != "rel=self&resource=acct%3Aalice%40example.com" {
+		t.Errorf("unexpected query %q", gotQuery)
+	}
+
+	if len(got.Links) != 1 || got.Links[0].Rel != "self" {
+		t.Errorf("expected only the self link, got %+v", got.Links)
+	}
+
+	if _, err := c.Lookup(context.Background(), "acct:bob@example.com"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Lookup() error = %v, want %v", err, client.ErrNotFound)
+	}
+}
+
+func TestClient_Lookup_HostMeta(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		hostMeta string
+		lrdd     string
+		wantErr  error
+	}{
+		{
+			name: "XRD",
+			hostMeta: `<?xml version="1.0" encoding="UTF-8"?>
+<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0">
+  <Link rel="lrdd" type="application/xrd+xml" template="https://example.com/lrdd?uri={uri}"/>
+</XRD>`,
+			lrdd: `<?xml version="1.0" encoding="UTF-8"?>
+<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0">
+  <Subject>acct:alice@example.com</Subject>
+  <Property type="http://schema.org/name">Alice</Property>
+  <Link rel="self" href="https://example.com/users/alice"/>
+</XRD>`,
+		},
+		{
+			name:     "JRD",
+			hostMeta: `{"links": [{"rel": "lrdd", "type": "application/jrd+json", "template": "https://example.com/lrdd?uri={uri}"}]}`,
+			lrdd:     `{"subject": "acct:alice@example.com", "properties": {"http://schema.org/name": "Alice"}, "links": [{"rel": "self", "href": "https://example.com/users/alice"}]}`,
+		},
+		{
+			name:     "HTTP template",
+			hostMeta: `{"links": [{"rel": "lrdd", "type": "application/jrd+json", "template": "http://example.com/lrdd?uri={uri}"}]}`,
+			lrdd:     `{"subject": "acct:alice@example.com", "properties": {"http://schema.org/name": "Alice"}, "links": [{"rel": "self", "href": "https://example.com/users/alice"}]}`,
+			wantErr:  client.ErrInsecureRedirect,
+		},
+	}
+
+	want := &webfingers.WebFinger{
+		Subject:    "acct:alice@example.com",
+		Properties: map[string]string{"http://schema.org/name": "Alice"},
+		Links:      []webfingers.Link{{Rel: "self", Href: "https://example.com/users/alice"}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/.well-known/host-meta":
+					fmt.Fprint(w, tc.hostMeta)
+				case r.URL.Path == "/lrdd" && r.URL.Query().Get("uri") == "acct:alice@example.com":
+					fmt.Fprint(w, tc.lrdd)
+				default:
+					// Servers without the endpoint serve their error page
+					w.Header().Set("Content-Type", "text/html")
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, "<html><body>Not Found</body></html>")
+				}
+			}))
+			defer srv.Close()
+
+			got, err := newClient(t, srv).Lookup(context.Background(), "acct:alice@example.com")
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("Lookup() error = %v, want %v", err, tc.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Lookup() = %+v, want %+v", got, want)
+			}
+
+			// The fallback can be disabled
+			_, err = newClient(t, srv, client.WithoutHostMeta()).Lookup(context.Background(), "acct:alice@example.com")
+			if !errors.Is(err, client.ErrNotFound) {
+				t.Errorf("Lookup() error = %v, want %v", err, client.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestClient_Lookup_NoFallback(t *testing.T) {
+	t.Parallel()
+
+	hostMeta := false
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/host-meta" {
+			hostMeta = true
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	// A 404 that isn't a page means the endpoint doesn't know the resource
+	_, err := newClient(t, srv).Lookup(context.Background(), "acct:alice@example.com")
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Lookup() error = %v, want %v", err, client.ErrNotFound)
+	}
+
+	if hostMeta {
+		t.Error("Lookup() fetched the host-meta")
+	}
+}
+
+func TestClient_Lookup_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr error
+	}{
+		{
+			name: "not JSON",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "not a document")
+			},
+			wantErr: client.ErrInvalidJRD,
+		},
+		{
+			name: "missing subject",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"links": []}`)
+			},
+			wantErr: client.ErrInvalidJRD,
+		},
+		{
+			name: "link without rel",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"subject": "acct:alice@example.com", "links": [{"href": "https://example.com"}]}`)
+			},
+			wantErr: client.ErrInvalidJRD,
+		},
+		{
+			name: "wrong types",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"subject": "acct:alice@example.com", "properties": {"name": 42}}`)
+			},
+			wantErr: client.ErrInvalidJRD,
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "oops", http.StatusInternalServerError)
+			},
+			wantErr: client.ErrUnexpectedStatus,
+		},
+		{
+			name: "redirect to HTTP",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://example.com/.well-known/webfinger", http.StatusFound)
+			},
+			wantErr: client.ErrInsecureRedirect,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewTLSServer(tc.handler)
+			defer srv.Close()
+
+			_, err := newClient(t, srv, client.WithoutHostMeta()).Lookup(context.Background(), "acct:alice@example.com")
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Lookup() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
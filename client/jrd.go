@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+// ErrInvalidJRD is returned when a response is not a valid JRD document.
+var ErrInvalidJRD = errors.New("invalid JRD")
+
+// jrdLink is a link of a JRD document (RFC 7033, section 4.4.4).
+type jrdLink struct {
+	Rel        string             `json:"rel"`
+	Type       string             `json:"type,omitempty"`
+	Href       string             `json:"href,omitempty"`
+	Template   string             `json:"template,omitempty"`
+	Titles     map[string]string  `json:"titles,omitempty"`
+	Properties map[string]*string `json:"properties,omitempty"`
+}
+
+// jrd is a JRD document (RFC 7033, section 4.4), which also describes
+// host-meta documents (RFC 6415).
+type jrd struct {
+	Subject    string             `json:"subject"`
+	Aliases    []string           `json:"aliases,omitempty"`
+	Properties map[string]*string `json:"properties,omitempty"`
+	Links      []jrdLink          `json:"links,omitempty"`
+}
+
+// xrd is the XML version of a JRD document, which host-meta and LRDD
+// responses may use.
+type xrd struct {
+	Subject    string   `xml:"Subject"`
+	Aliases    []string `xml:"Alias"`
+	Properties []struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"Property"`
+	Links []struct {
+		Rel      string `xml:"rel,attr"`
+		Type     string `xml:"type,attr"`
+		Href     string `xml:"href,attr"`
+		Template string `xml:"template,attr"`
+	} `xml:"Link"`
+}
+
+// DecodeJRD decodes and validates a JRD document, keeping only the links
+// of the rels if any are given.
+func DecodeJRD(body []byte, rels ...string) (*webfingers.WebFinger, error) {
+	doc, err := parseJRD(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+
+	return doc.webFinger(rels), nil
+}
+
+func parseJRD(body []byte) (*jrd, error) {
+	var doc jrd
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJRD, err)
+	}
+
+	return &doc, nil
+}
+
+// parseDocument decodes a JRD document, or an XRD one if it looks like XML.
+func parseDocument(body []byte) (*jrd, error) {
+	body = bytes.TrimSpace(body)
+
+	if !bytes.HasPrefix(body, []byte("<")) {
+		return parseJRD(body)
+	}
+
+	var x xrd
+	if err := xml.Unmarshal(body, &x); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJRD, err)
+	}
+
+	doc := &jrd{Subject: x.Subject, Aliases: x.Aliases}
+
+	if len(x.Properties) > 0 {
+		doc.Properties = make(map[string]*string, len(x.Properties))
+
+		for _, p := range x.Properties {
+			value := p.Value
+			doc.Properties[p.Type] = &value
+		}
+	}
+
+	for _, l := range x.Links {
+		doc.Links = append(doc.Links, jrdLink{Rel: l.Rel, Type: l.Type, Href: l.Href, Template: l.Template})
+	}
+
+	return doc, nil
+}
+
+// validate checks the document against RFC 7033. The subject, aliases and
+// hrefs must be URIs, and links need a rel. Property names aren't checked
+// since many servers, this one included, don't use URIs for them.
+func (doc *jrd) validate() error {
+	if doc.Subject == "" {
+		return fmt.Errorf("%w: missing subject", ErrInvalidJRD)
+	}
+
+	if !isURI(doc.Subject) {
+		return fmt.Errorf("%w: subject %q is not a URI", ErrInvalidJRD, doc.Subject)
+	}
+
+	for _, alias := range doc.Aliases {
+		if !isURI(alias) {
+			return fmt.Errorf("%w: alias %q is not a URI", ErrInvalidJRD, alias)
+		}
+	}
+
+	for _, l := range doc.Links {
+		if l.Rel == "" {
+			return fmt.Errorf("%w: link without a rel", ErrInvalidJRD)
+		}
+
+		if l.Href != "" && !isURI(l.Href) {
+			return fmt.Errorf("%w: href %q of link %s is not a URI", ErrInvalidJRD, l.Href, l.Rel)
+		}
+	}
+
+	return nil
+}
+
+// webFinger converts the document to a webfinger, keeping only the links
+// with an href and the rels, if any.
+func (doc *jrd) webFinger(rels []string) *webfingers.WebFinger {
+	finger := &webfingers.WebFinger{Subject: doc.Subject}
+
+	if len(doc.Properties) > 0 {
+		finger.Properties = make(map[string]string, len(doc.Properties))
+
+		for name, value := range doc.Properties {
+			if value != nil {
+				finger.Properties[name] = *value
+			} else {
+				finger.Properties[name] = ""
+			}
+		}
+	}
+
+	for _, l := range doc.Links {
+		if l.Href == "" || !hasRel(rels, l.Rel) {
+			continue
+		}
+
+		finger.Links = append(finger.Links, webfingers.Link{Rel: l.Rel, Href: l.Href})
+	}
+
+	return finger
+}
+
+// hasRel reports whether rel is in rels, or if there are no rels at all.
+func hasRel(rels []string, rel string) bool {
+	if len(rels) == 0 {
+		return true
+	}
+
+	for _, r := range rels {
+		if r == rel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isURI reports whether s is an absolute URI.
+func isURI(s string) bool {
+	u, err := url.Parse(s)
+
+	return err == nil && u.Scheme != ""
+}
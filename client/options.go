@@ -0,0 +1,28 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// defaultTimeout is the timeout of the default HTTP client.
+const defaultTimeout = 10 * time.Second
+
+// Option configures the client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for the lookups. Redirects to
+// anything but HTTPS are refused unless the client checks them itself.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		cl.httpClient = c
+	}
+}
+
+// WithoutHostMeta disables the host-meta fallback, so only the webfinger
+// endpoint is used.
+func WithoutHostMeta() Option {
+	return func(cl *Client) {
+		cl.hostMeta = false
+	}
+}
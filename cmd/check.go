@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/conformance"
+	"github.com/peterbourgon/ff/v4"
+)
+
+// exitCheckFailed is the exit code of checks with failures.
+const exitCheckFailed = 1
+
+var errCheckArgs = errors.New("expected a URL and a resource")
+
+func newCheckCmd() *ff.Command {
+	fs := ff.NewFlagSet("check")
+	format := fs.StringEnum(0, "format", "Output format: table or json", formatTable, formatJSON)
+	timeout := fs.Duration(0, "timeout", 10*time.Second, "Timeout of each request") //nolint:gomnd // Default timeout
+	insecure := fs.Bool('k', "insecure", "Skip TLS certificate verification")
+	httpURL := fs.String(0, "http-url", "", "Plain HTTP URL to check for redirects to HTTPS (default: the URL over http)")
+
+	return &ff.Command{
+		Name:      "check",
+		Usage:     "check [flags] <url> <resource>",
+		ShortHelp: "Check a webfinger server against RFC 7033",
+		LongHelp:  "The resource must exist on the server. Exits with 1 if any check fails.",
+		Flags:     fs,
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) != 2 { //nolint:gomnd // URL and resource
+				return errCheckArgs
+			}
+
+			s := &conformance.Suite{
+				URL:      args[0],
+				Resource: args[1],
+				HTTPURL:  *httpURL,
+				Client:   newHTTPClient(*timeout, *insecure),
+			}
+
+			results := s.Run(ctx)
+
+			var err error
+			if *format == formatJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(results)
+			} else {
+				err = writeCheckTable(results)
+			}
+
+			if err != nil {
+				return fmt.Errorf("error writing results: %w", err)
+			}
+
+			for _, r := range results {
+				if r.Status == conformance.StatusFail {
+					return &ExitError{Code: exitCheckFailed}
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// writeCheckTable prints the results as a table followed by a summary.
+func writeCheckTable(results []conformance.Result) error {
+	counts := make(map[conformance.Status]int)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd // Column padding
+	fmt.Fprintln(w, "STATUS\tCHECK\tMESSAGE")
+
+	for _, r := range results {
+		counts[r.Status]++
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Status, r.Check, r.Message)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err //nolint:wrapcheck // Writing to stdout
+	}
+
+	_, err := fmt.Fprintf(os.Stdout, "\n%d passed, %d warned, %d failed\n",
+		counts[conformance.StatusPass], counts[conformance.StatusWarn], counts[conformance.StatusFail])
+
+	return err //nolint:wrapcheck // Writing to stdout
+}
@@ -20,19 +20,14 @@ func Run(version string) error {
 	// Allow graceful shutdown
 	trapSignalsCrossPlatform(cancel)
 
-	cfg := &config.Config{}
-
-	// Create a new root command
-	subcommands := []*ff.Command{
-		newServerCmd(cfg),
-		newHealthcheckCmd(cfg),
-	}
-	cmd := newRootCmd(version, cfg, subcommands)
+	cfg := &config.Config{Version: version}
+	file := newConfigFile()
+	cmd := newCmd(version, cfg, file)
 
 	// Parse and run
-	if err := cmd.ParseAndRun(ctx, os.Args[1:], ff.WithEnvVarPrefix("WF")); err != nil {
+	if err := cmd.ParseAndRun(ctx, os.Args[1:], parseOptions(file)...); err != nil {
 		if errors.Is(err, ff.ErrHelp) || errors.Is(err, ff.ErrNoExec) {
-			fmt.Fprintf(os.Stderr, "\n%s\n", ffhelp.Command(cmd))
+			fmt.Fprintf(os.Stderr, "\n%s\n", ffhelp.Command(cmd.GetSelected()))
 
 			return nil
 		}
@@ -43,14 +38,32 @@ func Run(version string) error {
 	return nil
 }
 
+// ExitError makes the process exit with Code, printing Err if set.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 // https://github.com/caddyserver/caddy/blob/fbb0ecfa322aa7710a3448453fd3ae40f037b8d1/sigtrap.go#L37
-// trapSignalsCrossPlatform captures SIGINT or interrupt (depending
-// on the OS), which initiates a graceful shutdown. A second SIGINT
-// or interrupt will forcefully exit the process immediately.
+// trapSignalsCrossPlatform captures SIGINT, SIGTERM or interrupt
+// (depending on the OS), which initiates a graceful shutdown. A second
+// signal will forcefully exit the process immediately.
 func trapSignalsCrossPlatform(cancel context.CancelFunc) {
 	go func() {
 		shutdown := make(chan os.Signal, 1)
-		signal.Notify(shutdown, os.Interrupt, syscall.SIGINT)
+		signal.Notify(shutdown, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 		for i := 0; true; i++ {
 			<-shutdown
@@ -66,12 +79,46 @@ func trapSignalsCrossPlatform(cancel context.CancelFunc) {
 	}()
 }
 
+// newCmd creates the root command with all of its subcommands.
+func newCmd(version string, cfg *config.Config, file *configFile) *ff.Command {
+	subcommands := []*ff.Command{
+		newServerCmd(cfg),
+		newHealthcheckCmd(cfg),
+		newConfigCmd(version, file),
+		newResourceCmd(cfg),
+		newDiffCmd(cfg),
+		newQueryCmd(cfg),
+		newLookupCmd(),
+		newCheckCmd(),
+		newExportCmd(cfg),
+	}
+
+	return newRootCmd(version, cfg, subcommands)
+}
+
+// parseOptions reads the config from flags, then env vars, then the
+// config file.
+func parseOptions(file *configFile) []ff.Option {
+	return []ff.Option{
+		ff.WithEnvVarPrefix(envVarPrefix),
+		ff.WithConfigFileFlag("config"),
+		ff.WithConfigFileParser(file.Parse),
+	}
+}
+
 // NewRootCmd parses the command line flags and returns a config.Config struct.
 func newRootCmd(version string, cfg *config.Config, subcommands []*ff.Command) *ff.Command {
 	fs := ff.NewFlagSet(appName)
 
+	// Subcommands may define their own flags, which are added to the root ones
 	for _, cmd := range subcommands {
-		cmd.Flags = ff.NewFlagSet(cmd.Name).SetParent(fs)
+		cmdFs, ok := cmd.Flags.(*ff.FlagSet)
+		if !ok {
+			cmdFs = ff.NewFlagSet(cmd.Name)
+			cmd.Flags = cmdFs
+		}
+
+		cmdFs.SetParent(fs)
 	}
 
 	cmd := &ff.Command{
@@ -88,11 +135,55 @@ func newRootCmd(version string, cfg *config.Config, subcommands []*ff.Command) *
 		defaultHost = "0.0.0.0"
 	}
 
+	fs.StringVar(&cfg.ConfigPath, 'c', "config", "", "Path to a YAML or JSON config file")
 	fs.BoolVar(&cfg.Debug, 'd', "debug", "Enable debug logging")
-	fs.StringVar(&cfg.Host, 'h', "host", defaultHost, "Host to listen on")
+	fs.StringEnumVar(&cfg.LogFormat, 0, "log-format", "Log format: json or text", config.LogFormatJSON, config.LogFormatText)
+	fs.StringEnumVar(&cfg.LogLevel, 0, "log-level", "Log level: info, debug, warn or error", "info", "debug", "warn", "error")
+	fs.StringVar(&cfg.LogOutput, 0, "log-output", config.LogOutputStderr, "Where to write logs: stderr, stdout, syslog or a file path")
+	fs.IntVar(&cfg.LogMaxSize, 0, "log-max-size", config.DefaultLogMaxSize, "Size in megabytes at which the log file is rotated (0 disables it)")
+	fs.IntVar(&cfg.LogMaxBackups, 0, "log-max-backups", config.DefaultLogMaxBackups, "Number of rotated log files to keep")
+	fs.StringVar(&cfg.LogSyslogAddr, 0, "log-syslog-addr", config.DefaultLogSyslogAddr, "Address of the syslog daemon, as unix:/path or udp:host:port")
+	fs.StringEnumVar(&cfg.LogLookups, 0, "log-lookups", "How looked up resources and rels are logged: plain, hash or none", config.LogLookupsPlain, config.LogLookupsHash, config.LogLookupsNone)
+	fs.StringVar(&cfg.LogLookupsKey, 0, "log-lookups-key", "", "Key of the hashes of looked up resources and rels (random if empty)")
+	fs.StringVar(&cfg.Host, 'h', "host", defaultHost, "Host to listen on, or unix:/path to listen on a Unix socket")
 	fs.StringVar(&cfg.Port, 'p', "port", "8080", "Port to listen on")
 	fs.StringVar(&cfg.URNPath, 'u', "urn-file", "urns.yml", "Path to the URNs file")
 	fs.StringVar(&cfg.FingerPath, 'f', "finger-file", "fingers.yml", "Path to the fingers file")
+	fs.DurationVar(&cfg.CacheMaxAge, 0, "cache-max-age", 0, "Cache-Control max-age of webfinger responses (0 disables it)")
+	fs.StringListVar(&cfg.CacheResourceMaxAge, 0, "cache-resource-max-age", "Per resource Cache-Control max-age as resource=duration (repeatable)")
+	fs.BoolVarDefault(&cfg.Compress, 0, "compress", true, "Gzip responses for clients that accept it")
+	fs.IntVar(&cfg.CompressMinSize, 0, "compress-min-size", config.DefaultCompressMinSize, "Minimum response size in bytes to be compressed")
+	fs.StringVar(&cfg.TLSCert, 0, "tls-cert", "", "Path to the TLS certificate. Enables HTTPS")
+	fs.StringVar(&cfg.TLSKey, 0, "tls-key", "", "Path to the TLS private key")
+	fs.StringVar(&cfg.HTTPRedirectPort, 0, "http-redirect-port", "", "Port of an HTTP listener that redirects to HTTPS")
+	fs.StringVar(&cfg.SocketMode, 0, "socket-mode", config.DefaultSocketMode, "File mode of the Unix socket")
+	fs.DurationVar(&cfg.ReadTimeout, 0, "read-timeout", config.DefaultReadTimeout, "Maximum duration for reading the entire request (0 disables it)")
+	fs.DurationVar(&cfg.WriteTimeout, 0, "write-timeout", config.DefaultWriteTimeout, "Maximum duration for writing the response (0 disables it)")
+	fs.DurationVar(&cfg.IdleTimeout, 0, "idle-timeout", config.DefaultIdleTimeout, "Maximum time to wait for the next request on a keep-alive connection (0 disables it)")
+	fs.DurationVar(&cfg.ReadHeaderTimeout, 0, "read-header-timeout", config.DefaultReadHeaderTimeout, "Maximum duration for reading the request headers (0 disables it)")
+	fs.DurationVar(&cfg.RequestTimeout, 0, "request-timeout", config.DefaultRequestTimeout, "Maximum duration for handling a request (0 disables it)")
+	fs.DurationVar(&cfg.ShutdownTimeout, 0, "shutdown-timeout", config.DefaultShutdownTimeout, "Time given to open connections to finish on shutdown before they are closed (0 waits forever)")
+	fs.BoolVar(&cfg.Metrics, 0, "metrics", "Expose Prometheus metrics on /metrics of the admin address")
+	fs.StringVar(&cfg.AdminAddr, 0, "admin-addr", "", "Address of a separate listener for admin endpoints like /metrics")
+	fs.StringVar(&cfg.AdminTokensFile, 0, "admin-tokens-file", "", "File with the bearer tokens of the admin API. Enables the API on the admin address")
+	fs.BoolVar(&cfg.AdminPersist, 0, "admin-persist", "Save changes made through the admin API to the fingers and URNs files")
+	fs.StringVar(&cfg.AuditFile, 0, "audit-file", "", "File every change to the webfingers is appended to as JSON lines")
+	fs.DurationVar(&cfg.ReadyMaxAge, 0, "ready-max-age", 0, "Maximum age of the loaded webfingers before the server is not ready (0 disables it)")
+	fs.BoolVar(&cfg.Analytics, 0, "analytics", "Count the top looked up resources and expose them on /analytics of the admin address")
+	fs.IntVar(&cfg.AnalyticsTop, 0, "analytics-top", config.DefaultAnalyticsTop, "Number of top resources reported")
+	fs.DurationVar(&cfg.AnalyticsWindow, 0, "analytics-window", config.DefaultAnalyticsWindow, "Time window of the analytics")
+	fs.StringVar(&cfg.AnalyticsFile, 0, "analytics-file", "", "File the analytics are appended to, as CSV if it ends in .csv or JSON lines otherwise")
+	fs.DurationVar(&cfg.AnalyticsFlushInterval, 0, "analytics-flush-interval", config.DefaultAnalyticsFlushInterval, "How often the analytics are appended to their file")
+	fs.Float64Var(&cfg.RateLimit, 0, "rate-limit", 0, "Webfinger requests per second allowed per client IP (0 disables it)")
+	fs.IntVar(&cfg.RateLimitBurst, 0, "rate-limit-burst", config.DefaultRateLimitBurst, "Webfinger requests a client IP can make at once")
+	fs.StringListVar(&cfg.RateLimitAllowlist, 0, "rate-limit-allowlist", "CIDR of clients that are never rate limited (repeatable)")
+	fs.StringListVar(&cfg.TrustedProxies, 0, "trusted-proxies", "CIDR of proxies whose forwarding headers are trusted (repeatable)")
+	fs.StringVar(&cfg.ClientIPHeader, 0, "client-ip-header", config.DefaultClientIPHeader, "Header the trusted proxies set to the client IP: Forwarded, X-Forwarded-For or X-Real-IP")
+	fs.BoolVar(&cfg.TrustUnixSocket, 0, "trust-unix-socket", "Trust the client IP header over the Unix socket, which only the proxy must be able to reach")
+	fs.IntVar(&cfg.BanThreshold, 0, "ban-threshold", 0, "Misses within the ban window that get a client IP banned (0 disables it)")
+	fs.DurationVar(&cfg.BanWindow, 0, "ban-window", config.DefaultBanWindow, "Window in which misses are counted")
+	fs.DurationVar(&cfg.BanDuration, 0, "ban-duration", config.DefaultBanDuration, "How long clients stay banned")
+	fs.DurationVar(&cfg.BanTarpit, 0, "ban-tarpit", 0, "Delay the responses of banned clients by this instead of blocking them")
 
 	return cmd
 }
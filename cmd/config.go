@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"github.com/peterbourgon/ff/v4"
+)
+
+// envVarPrefix is the prefix of the env vars that set flags.
+const envVarPrefix = "WF"
+
+// Sources of config values, from highest to lowest precedence.
+const (
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+	sourceFile    = "file"
+	sourceDefault = "default"
+)
+
+// secretFlags are the flags whose values config print hides.
+var secretFlags = map[string]bool{
+	"log-lookups-key": true,
+}
+
+// redactedValue is printed instead of the values of secret flags.
+const redactedValue = "<redacted>"
+
+// configFile parses the config file and keeps track of the settings in it.
+type configFile struct {
+	keys map[string]bool
+}
+
+func newConfigFile() *configFile {
+	return &configFile{keys: map[string]bool{}}
+}
+
+// Parse is an ff.ConfigFileParseFunc for YAML and JSON config files.
+// Settings whose env var is set are skipped, even if it's empty, since env
+// vars take precedence over the file.
+func (f *configFile) Parse(r io.Reader, set func(name, value string) error) error {
+	return config.ParseFile(r, func(name, value string) error { //nolint:wrapcheck // Already a config file error
+		if envVarSet(name) {
+			return nil
+		}
+
+		f.keys[name] = true
+
+		return set(name, value)
+	})
+}
+
+func newConfigCmd(version string, file *configFile) *ff.Command {
+	fs := ff.NewFlagSet("config")
+	printFs := ff.NewFlagSet("print").SetParent(fs)
+
+	printCmd := &ff.Command{
+		Name:      "print",
+		Usage:     "config print [flags]",
+		ShortHelp: "Print the effective config and where each value came from",
+		Flags:     printFs,
+		Exec: func(ctx context.Context, args []string) error {
+			cli := cliFlags(version, os.Args[1:])
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd // Column padding
+			fmt.Fprintln(w, "NAME\tVALUE\tSOURCE")
+
+			_ = printFs.WalkFlags(func(f ff.Flag) error {
+				name := flagName(f)
+
+				var source string
+
+				switch {
+				case cli[name]:
+					source = sourceFlag
+				case envVarSet(name):
+					source = sourceEnv
+				case file.keys[name] || file.keys[envVarKey(name)]:
+					source = sourceFile
+				default:
+					source = sourceDefault
+				}
+
+				value := f.GetValue()
+				if secretFlags[name] && value != "" {
+					value = redactedValue
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\n", name, value, source)
+
+				return nil
+			})
+
+			return w.Flush() //nolint:wrapcheck // Writing to stdout
+		},
+	}
+
+	return &ff.Command{
+		Name:        "config",
+		Usage:       "config <command> [flags]",
+		ShortHelp:   "Inspect the config",
+		Flags:       fs,
+		Subcommands: []*ff.Command{printCmd},
+	}
+}
+
+// cliFlags returns the names of the flags set on the command line, by
+// parsing the args again without env vars or the config file.
+func cliFlags(version string, args []string) map[string]bool {
+	probe := newCmd(version, &config.Config{}, newConfigFile())
+	_ = probe.Parse(args)
+
+	set := map[string]bool{}
+
+	selected := probe.GetSelected()
+	if selected == nil || selected.Flags == nil {
+		return set
+	}
+
+	_ = selected.Flags.WalkFlags(func(f ff.Flag) error {
+		if f.IsSet() {
+			set[flagName(f)] = true
+		}
+
+		return nil
+	})
+
+	return set
+}
+
+// flagName returns the long name of the flag, or its short one.
+func flagName(f ff.Flag) string {
+	if name, ok := f.GetLongName(); ok {
+		return name
+	}
+
+	short, _ := f.GetShortName()
+
+	return string(short)
+}
+
+// envVarSet reports whether the env var of the flag is set, even if it's
+// empty.
+func envVarSet(name string) bool {
+	_, ok := os.LookupEnv(envVarKey(name))
+
+	return ok
+}
+
+// envVarKey returns the env var that sets the flag.
+func envVarKey(name string) string {
+	return envVarPrefix + "_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", "/", "_").Replace(name))
+}
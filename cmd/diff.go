@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"git.maronato.dev/maronato/finger/internal/audit"
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
+	"git.maronato.dev/maronato/finger/webfingers"
+	"github.com/peterbourgon/ff/v4"
+)
+
+// Output formats of commands that print results.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// Exit codes of the diff command, like diff(1).
+const (
+	exitDiffChanged = 1
+	exitDiffError   = 2
+)
+
+var errDiffArgs = errors.New("expected the old and new fingers files")
+
+// fingersDiff are the webfingers that changed between two configurations.
+type fingersDiff struct {
+	Added   []audit.Change `json:"added"`
+	Removed []audit.Change `json:"removed"`
+	Changed []audit.Change `json:"changed"`
+}
+
+func newDiffCmd(cfg *config.Config) *ff.Command {
+	fs := ff.NewFlagSet("diff")
+	format := fs.StringEnum(0, "format", "Output format: text or json", formatText, formatJSON)
+	oldURNPath := fs.String(0, "old-urn-file", "", "Path to the URNs file of the old fingers (default: --urn-file)")
+	newURNPath := fs.String(0, "new-urn-file", "", "Path to the URNs file of the new fingers (default: --urn-file)")
+
+	return &ff.Command{
+		Name:      "diff",
+		Usage:     "diff [flags] <old fingers file> <new fingers file>",
+		ShortHelp: "Show how the served webfingers change between two fingers files",
+		LongHelp:  "Exits with 0 if nothing changed, 1 if something did and 2 on errors.",
+		Flags:     fs,
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) != 2 { //nolint:gomnd // Old and new files
+				return &ExitError{Code: exitDiffError, Err: errDiffArgs}
+			}
+
+			ctx = withCLILogger(ctx, cfg)
+
+			prev, err := loadFingers(ctx, cfg, args[0], *oldURNPath)
+			if err != nil {
+				return &ExitError{Code: exitDiffError, Err: err}
+			}
+
+			next, err := loadFingers(ctx, cfg, args[1], *newURNPath)
+			if err != nil {
+				return &ExitError{Code: exitDiffError, Err: err}
+			}
+
+			var d fingersDiff
+			d.Added, d.Removed, d.Changed = audit.Diff(prev, next)
+
+			if *format == formatJSON {
+				err = writeDiffJSON(os.Stdout, d)
+			} else {
+				err = writeDiffText(os.Stdout, d)
+			}
+
+			if err != nil {
+				return &ExitError{Code: exitDiffError, Err: err}
+			}
+
+			if len(d.Added)+len(d.Removed)+len(d.Changed) > 0 {
+				return &ExitError{Code: exitDiffChanged}
+			}
+
+			return nil
+		},
+	}
+}
+
+// loadFingers loads the webfingers of a fingers file like the server does,
+// with the URNs file of the config unless urnPath is set.
+func loadFingers(ctx context.Context, cfg *config.Config, fingerPath, urnPath string) (webfingers.WebFingers, error) {
+	c := *cfg
+	c.FingerPath = fingerPath
+
+	if urnPath != "" {
+		c.URNPath = urnPath
+	}
+
+	fingers, err := fingerreader.Load(ctx, &c)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", fingerPath, err)
+	}
+
+	return fingers, nil
+}
+
+func writeDiffJSON(w io.Writer, d fingersDiff) error {
+	// Always output lists
+	for _, list := range []*[]audit.Change{&d.Added, &d.Removed, &d.Changed} {
+		if *list == nil {
+			*list = []audit.Change{}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(d); err != nil {
+		return fmt.Errorf("error encoding diff: %w", err)
+	}
+
+	return nil
+}
+
+// writeDiffText writes the changed subjects and their fields, marked with
+// + if they were added, - if removed and ~ if changed.
+func writeDiffText(w io.Writer, d fingersDiff) error {
+	ew := &errWriter{w: w}
+
+	for _, list := range []struct {
+		mark    string
+		changes []audit.Change
+	}{
+		{"+", d.Added},
+		{"-", d.Removed},
+		{"~", d.Changed},
+	} {
+		for _, c := range list.changes {
+			ew.printf("%s %s\n", list.mark, c.Subject)
+
+			for _, f := range c.Fields {
+				switch {
+				case f.Old == "":
+					ew.printf("    + %s %s: %s\n", f.Type, f.Name, f.New)
+				case f.New == "":
+					ew.printf("    - %s %s: %s\n", f.Type, f.Name, f.Old)
+				default:
+					ew.printf("    ~ %s %s: %s -> %s\n", f.Type, f.Name, f.Old, f.New)
+				}
+			}
+		}
+	}
+
+	if len(d.Added)+len(d.Removed)+len(d.Changed) == 0 {
+		ew.printf("No changes\n")
+	} else {
+		ew.printf("\n%d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+	}
+
+	if ew.err != nil {
+		return fmt.Errorf("error writing diff: %w", ew.err)
+	}
+
+	return nil
+}
+
+// errWriter keeps the first error of a series of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, a ...any) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, a...)
+	}
+}
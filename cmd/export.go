@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/export"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
+	"github.com/peterbourgon/ff/v4"
+)
+
+var errExportOut = errors.New("expected an output directory with --out")
+
+func newExportCmd(cfg *config.Config) *ff.Command {
+	fs := ff.NewFlagSet("export")
+	out := fs.String('o', "out", "", "Directory to write the static files to")
+
+	return &ff.Command{
+		Name:      "export",
+		Usage:     "export --out <dir>",
+		ShortHelp: "Export the webfingers as static files with rewrite rules",
+		LongHelp:  "Writes the JRD of every resource, plus rewrite rules for nginx, Apache, Netlify and Caddy that serve them.",
+		Flags:     fs,
+		Exec: func(ctx context.Context, args []string) error {
+			if *out == "" || len(args) > 0 {
+				return errExportOut
+			}
+
+			ctx = withCLILogger(ctx, cfg)
+
+			fingers, err := fingerreader.Load(ctx, cfg)
+			if err != nil {
+				return fmt.Errorf("error loading finger files: %w", err)
+			}
+
+			entries, err := export.Write(*out, fingers)
+			if err != nil {
+				return fmt.Errorf("error exporting webfingers: %w", err)
+			}
+
+			fmt.Printf("Exported %d resources to %s\n", len(entries), *out) //nolint:forbidigo // We want to print to stdout
+
+			return nil
+		},
+	}
+}
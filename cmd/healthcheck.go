@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -12,42 +14,95 @@ import (
 )
 
 func newHealthcheckCmd(cfg *config.Config) *ff.Command {
+	fs := ff.NewFlagSet("healthcheck")
+	insecure := fs.Bool('k', "insecure", "Skip TLS certificate verification")
+	ready := fs.Bool(0, "ready", "Check if the server is ready to serve instead of just running")
+	resource := fs.String(0, "resource", "", "Also check that this resource can be looked up")
+
 	return &ff.Command{
 		Name:      "healthcheck",
 		Usage:     "healthcheck [flags]",
 		ShortHelp: "Check if the server is running",
+		Flags:     fs,
 		Exec: func(ctx context.Context, args []string) error {
+			transport := &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: *insecure, //nolint:gosec // Opt-in for self-signed certificates
+					MinVersion:         tls.VersionTLS12,
+				},
+			}
+
+			host := cfg.GetAddr()
+
+			// Connect through the socket if the server listens on one
+			if cfg.IsUnixSocket() {
+				host = "localhost"
+				transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", cfg.GetSocketPath())
+				}
+			}
+
 			// Create a new client
 			client := &http.Client{
-				Timeout: 5 * time.Second, //nolint:gomnd // We want to use a constant
+				Timeout:   5 * time.Second, //nolint:gomnd // We want to use a constant
+				Transport: transport,
 			}
 
-			// Create a new request
-			reqURL := url.URL{
-				Scheme: "http",
-				Host:   cfg.GetAddr(),
-				Path:   "/healthz",
+			// Use HTTPS if the server does
+			scheme := "http"
+			if cfg.TLSEnabled() {
+				scheme = "https"
 			}
 
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), http.NoBody)
-			if err != nil {
-				return fmt.Errorf("error creating request: %w", err)
+			// Check liveness or readiness
+			path := "/healthz"
+			if *ready {
+				path = "/readyz"
 			}
 
-			// Send the request
-			resp, err := client.Do(req)
-			if err != nil {
-				return fmt.Errorf("error sending request: %w", err)
-			}
+			checks := []url.URL{{Scheme: scheme, Host: host, Path: path}}
 
-			defer resp.Body.Close()
+			// And look up the resource, if any
+			if *resource != "" {
+				checks = append(checks, url.URL{
+					Scheme:   scheme,
+					Host:     host,
+					Path:     "/.well-known/webfinger",
+					RawQuery: url.Values{"resource": {*resource}}.Encode(),
+				})
+			}
 
-			// Check the response
-			if resp.StatusCode != http.StatusOK {
-				return fmt.Errorf("server returned status %d", resp.StatusCode) //nolint:goerr113 // We want to return an error
+			for _, reqURL := range checks {
+				if err := checkURL(ctx, client, reqURL.String()); err != nil {
+					return err
+				}
 			}
 
 			return nil
 		},
 	}
 }
+
+// checkURL sends a GET request to url and expects a 200 response.
+func checkURL(ctx context.Context, client *http.Client, url string) error {
+	// Create a new request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	// Send the request
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	// Check the response
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Path, resp.StatusCode) //nolint:goerr113 // We want to return an error
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"git.maronato.dev/maronato/finger/client"
+	"git.maronato.dev/maronato/finger/webfingers"
+	"github.com/peterbourgon/ff/v4"
+)
+
+// formatTable prints results as an aligned table.
+const formatTable = "table"
+
+var errLookupArgs = errors.New("expected exactly one resource")
+
+func newLookupCmd() *ff.Command {
+	fs := ff.NewFlagSet("lookup")
+	rels := fs.StringList(0, "rel", "Only return the links with this rel (repeatable)")
+	format := fs.StringEnum(0, "format", "Output format: table or json", formatTable, formatJSON)
+	timeout := fs.Duration(0, "timeout", 10*time.Second, "Timeout of the lookup") //nolint:gomnd // Default timeout
+	insecure := fs.Bool('k', "insecure", "Skip TLS certificate verification")
+	noHostMeta := fs.Bool(0, "no-host-meta", "Don't fall back to host-meta when the server has no webfinger endpoint")
+
+	return &ff.Command{
+		Name:      "lookup",
+		Usage:     "lookup [flags] <resource>",
+		ShortHelp: "Look up a resource on its webfinger server",
+		Flags:     fs,
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) != 1 {
+				return errLookupArgs
+			}
+
+			opts := []client.Option{client.WithHTTPClient(newHTTPClient(*timeout, *insecure))}
+			if *noHostMeta {
+				opts = append(opts, client.WithoutHostMeta())
+			}
+
+			finger, err := client.New(opts...).Lookup(ctx, args[0], *rels...)
+			if err != nil {
+				return fmt.Errorf("error looking up %s: %w", args[0], err)
+			}
+
+			if *format == formatJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+
+				return enc.Encode(finger) //nolint:wrapcheck // Writing to stdout
+			}
+
+			return writeFingerTable(finger)
+		},
+	}
+}
+
+// newHTTPClient creates the HTTP client of commands that query servers.
+func newHTTPClient(timeout time.Duration, insecure bool) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: insecure, //nolint:gosec // Opt-in for self-signed certificates
+				MinVersion:         tls.VersionTLS12,
+			},
+		},
+	}
+}
+
+// writeFingerTable prints the subject, properties and links of the
+// webfinger as a table.
+func writeFingerTable(finger *webfingers.WebFinger) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd // Column padding
+	fmt.Fprintln(w, "TYPE\tNAME\tVALUE")
+	fmt.Fprintf(w, "subject\t\t%s\n", finger.Subject)
+
+	names := make([]string, 0, len(finger.Properties))
+	for name := range finger.Properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "property\t%s\t%s\n", name, finger.Properties[name])
+	}
+
+	for _, l := range finger.Links {
+		fmt.Fprintf(w, "link\t%s\t%s\n", l.Rel, l.Href)
+	}
+
+	return w.Flush() //nolint:wrapcheck // Writing to stdout
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
+	"git.maronato.dev/maronato/finger/internal/server"
+	"git.maronato.dev/maronato/finger/webfingers"
+	"github.com/peterbourgon/ff/v4"
+)
+
+// exitQueryMiss is the exit code of lookups that found nothing.
+const exitQueryMiss = 1
+
+var errQueryArgs = errors.New("expected exactly one resource")
+
+func newQueryCmd(cfg *config.Config) *ff.Command {
+	fs := ff.NewFlagSet("query")
+	rels := fs.StringList(0, "rel", "Only return the links with this rel, like the rel query param (repeatable)")
+	headers := fs.Bool(0, "headers", "Also print the status and headers of the response")
+
+	return &ff.Command{
+		Name:      "query",
+		Usage:     "query [flags] <resource>",
+		ShortHelp: "Look up a resource in the fingers file like the server would",
+		LongHelp:  "Exits with 1 if the resource wasn't found.",
+		Flags:     fs,
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) != 1 {
+				return errQueryArgs
+			}
+
+			ctx = withCLILogger(ctx, cfg)
+			resource := args[0]
+
+			resources, urnAliases, err := fingerreader.LoadSource(ctx, cfg)
+			if err != nil {
+				return fmt.Errorf("error loading finger files: %w", err)
+			}
+
+			fingers, err := webfingers.NewWebFingers(resources, urnAliases)
+			if err != nil {
+				return fmt.Errorf("error parsing finger files: %w", err)
+			}
+
+			res, err := server.Query(ctx, cfg, fingers, resource, *rels)
+			if err != nil {
+				return fmt.Errorf("error looking up resource: %w", err)
+			}
+
+			if *headers {
+				printHeaders(res)
+			}
+
+			notes := server.ExplainQuery(fingers, urnAliases, resource, *rels)
+			for _, note := range notes {
+				fmt.Fprintf(os.Stderr, "%s\n", note)
+			}
+
+			if res.Status != http.StatusOK {
+				if len(notes) == 0 {
+					fmt.Fprintf(os.Stderr, "%s", res.Body)
+				}
+
+				return &ExitError{Code: exitQueryMiss}
+			}
+
+			// Indent the JRD for humans
+			var out bytes.Buffer
+			if err := json.Indent(&out, res.Body, "", "  "); err != nil {
+				return fmt.Errorf("error formatting response: %w", err)
+			}
+
+			fmt.Print(out.String()) //nolint:forbidigo // We want to print to stdout
+
+			return nil
+		},
+	}
+}
+
+// printHeaders prints the status line and headers of the result.
+func printHeaders(res *server.QueryResult) {
+	fmt.Printf("%d %s\n", res.Status, http.StatusText(res.Status)) //nolint:forbidigo // We want to print to stdout
+
+	names := make([]string, 0, len(res.Header))
+	for name := range res.Header {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, v := range res.Header[name] {
+			fmt.Printf("%s: %s\n", name, v) //nolint:forbidigo // We want to print to stdout
+		}
+	}
+
+	fmt.Println() //nolint:forbidigo // We want to print to stdout
+}
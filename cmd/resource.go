@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/webfingers"
+	"github.com/peterbourgon/ff/v4"
+)
+
+var errResourceArg = errors.New("expected exactly one resource")
+
+// fieldFlags are the flags that change the fields of a resource.
+type fieldFlags struct {
+	properties []string
+	links      []string
+	unset      []string
+}
+
+// register adds the flags to fs, with --unset only if the resource may
+// already have fields.
+func (f *fieldFlags) register(fs *ff.FlagSet, withUnset bool) {
+	fs.StringListVar(&f.properties, 0, "set", "Property as name=value (repeatable)")
+	fs.StringListVar(&f.links, 0, "link", "Link as rel=href, where rel may be a URN alias (repeatable)")
+
+	if withUnset {
+		fs.StringListVar(&f.unset, 0, "unset", "Name or rel of a field to remove (repeatable)")
+	}
+}
+
+// parseArgs returns the resource in args, and parses the flags after it
+// since flag parsing stops at the first argument.
+func (f *fieldFlags) parseArgs(parent *ff.FlagSet, name string, args []string, withUnset bool) (string, error) {
+	var trailing fieldFlags
+
+	resource, err := parseResourceArgs(parent, name, args, func(fs *ff.FlagSet) {
+		trailing.register(fs, withUnset)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	f.properties = append(f.properties, trailing.properties...)
+	f.links = append(f.links, trailing.links...)
+	f.unset = append(f.unset, trailing.unset...)
+
+	return resource, nil
+}
+
+// parseResourceArgs returns the resource in args, and parses the flags
+// after it with the ones register adds.
+func parseResourceArgs(parent *ff.FlagSet, name string, args []string, register func(fs *ff.FlagSet)) (string, error) {
+	if len(args) == 0 {
+		return "", errResourceArg
+	}
+
+	fs := ff.NewFlagSet(name).SetParent(parent)
+	register(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return "", fmt.Errorf("error parsing flags: %w", err)
+	}
+
+	if len(fs.GetArgs()) > 0 {
+		return "", errResourceArg
+	}
+
+	return args[0], nil
+}
+
+// apply changes the fields of a resource.
+func (f *fieldFlags) apply(fields map[string]string, urnAliases webfingers.URNAliases) error {
+	parsed, err := fingerreader.ParseFields(f.properties, f.links, urnAliases)
+	if err != nil {
+		return fmt.Errorf("error parsing fields: %w", err)
+	}
+
+	for _, name := range f.unset {
+		name = fingerreader.FieldName(urnAliases, name)
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("%w: %s is not set", fingerreader.ErrInvalidField, name)
+		}
+
+		delete(fields, name)
+	}
+
+	for name, value := range parsed {
+		fields[name] = value
+	}
+
+	return nil
+}
+
+func newResourceCmd(cfg *config.Config) *ff.Command {
+	fs := ff.NewFlagSet("resource")
+
+	var addFlags, setFlags fieldFlags
+
+	addFs := ff.NewFlagSet("add").SetParent(fs)
+	addFlags.register(addFs, false)
+
+	setFs := ff.NewFlagSet("set").SetParent(fs)
+	setFlags.register(setFs, true)
+
+	removeFs := ff.NewFlagSet("remove").SetParent(fs)
+
+	addCmd := &ff.Command{
+		Name:      "add",
+		Usage:     "resource add <resource> [flags]",
+		ShortHelp: "Add a resource to the fingers file",
+		Flags:     addFs,
+		Exec: func(ctx context.Context, args []string) error {
+			resource, err := addFlags.parseArgs(fs, "add", args, false)
+			if err != nil {
+				return err
+			}
+
+			return editResource(ctx, cfg, resource, true, addFlags.apply)
+		},
+	}
+
+	setCmd := &ff.Command{
+		Name:      "set",
+		Usage:     "resource set <resource> [flags]",
+		ShortHelp: "Change the fields of a resource in the fingers file",
+		Flags:     setFs,
+		Exec: func(ctx context.Context, args []string) error {
+			resource, err := setFlags.parseArgs(fs, "set", args, true)
+			if err != nil {
+				return err
+			}
+
+			return editResource(ctx, cfg, resource, false, setFlags.apply)
+		},
+	}
+
+	removeCmd := &ff.Command{
+		Name:      "remove",
+		Usage:     "resource remove <resource> [flags]",
+		ShortHelp: "Remove a resource from the fingers file",
+		Flags:     removeFs,
+		Exec: func(ctx context.Context, args []string) error {
+			resource, err := parseResourceArgs(fs, "remove", args, func(*ff.FlagSet) {})
+			if err != nil {
+				return err
+			}
+
+			key, err := fingerreader.RemoveResource(withCLILogger(ctx, cfg), cfg, resource)
+			if err != nil {
+				return fmt.Errorf("error removing resource: %w", err)
+			}
+
+			fmt.Printf("Removed %s\n", key) //nolint:forbidigo // We want to print to stdout
+
+			return nil
+		},
+	}
+
+	return &ff.Command{
+		Name:        "resource",
+		Usage:       "resource <command> [flags]",
+		ShortHelp:   "Edit the resources in the fingers file",
+		Flags:       fs,
+		Subcommands: []*ff.Command{addCmd, setCmd, removeCmd},
+	}
+}
+
+// editResource adds or changes a resource and prints the result.
+func editResource(
+	ctx context.Context,
+	cfg *config.Config,
+	resource string,
+	create bool,
+	edit func(fields map[string]string, urnAliases webfingers.URNAliases) error,
+) error {
+	key, err := fingerreader.EditResource(withCLILogger(ctx, cfg), cfg, resource, create, edit)
+	if err != nil {
+		return fmt.Errorf("error editing resource: %w", err)
+	}
+
+	verb := "Updated"
+	if create {
+		verb = "Added"
+	}
+
+	fmt.Printf("%s %s\n", verb, key) //nolint:forbidigo // We want to print to stdout
+
+	return nil
+}
+
+// withCLILogger adds a logger that writes to stderr to the context, for
+// commands that read the finger files.
+func withCLILogger(ctx context.Context, cfg *config.Config) context.Context {
+	return log.WithLogger(ctx, log.NewLogger(os.Stderr, cfg))
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"git.maronato.dev/maronato/finger/internal/config"
 	"git.maronato.dev/maronato/finger/internal/fingerreader"
@@ -20,20 +19,29 @@ func newServerCmd(cfg *config.Config) *ff.Command {
 		Usage:     "serve [flags]",
 		ShortHelp: "Start the webfinger server",
 		Exec: func(ctx context.Context, args []string) error {
-			// Create a logger and add it to the context
-			l := log.NewLogger(os.Stderr, cfg)
-			ctx = log.WithLogger(ctx, l)
+			// Validate the config
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("error validating config: %w", err)
+			}
 
-			// Read the webfinger files
-			r := fingerreader.NewFingerReader()
-			err := r.ReadFiles(cfg)
+			// Open the log output
+			out, err := log.OpenOutput(cfg)
 			if err != nil {
-				return fmt.Errorf("error reading finger files: %w", err)
+				return fmt.Errorf("error opening log output: %w", err)
 			}
+			defer out.Close()
 
-			fingers, err := r.ReadFingerFile(ctx)
+			// Create a logger and add it to the context
+			l := log.NewLogger(out, cfg)
+			ctx = log.WithLogger(ctx, l)
+
+			// Reopen the log output on SIGUSR1 so it works with logrotate
+			go log.ReopenOnSignal(ctx, out)
+
+			// Read the webfinger files
+			fingers, err := fingerreader.Load(ctx, cfg)
 			if err != nil {
-				return fmt.Errorf("error parsing finger files: %w", err)
+				return fmt.Errorf("error loading finger files: %w", err)
 			}
 
 			l.Info(fmt.Sprintf("Loaded %d webfingers", len(fingers)))
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// computeETag returns a strong ETag for the given response body.
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header value matches etag,
+// using the weak comparison defined in RFC 9110.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for ifNoneMatch != "" {
+		var candidate string
+
+		candidate, ifNoneMatch, _ = strings.Cut(ifNoneMatch, ",")
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified reports whether the request's preconditions allow
+// answering with 304 Not Modified.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	// If-None-Match takes precedence over If-Modified-Since
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		t, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+
+		// HTTP dates have second precision
+		return !lastModified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
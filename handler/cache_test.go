@@ -0,0 +1,178 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/handler"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+func TestWebfingerHandler_Caching(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {
+			Subject: "acct:user@example.com",
+			Links: []webfingers.Link{
+				{
+					Rel:  "http://webfinger.net/rel/profile-page",
+					Href: "https://example.com/user",
+				},
+				{
+					Rel:  "http://webfinger.net/rel/avatar",
+					Href: "https://example.com/avatar",
+				},
+			},
+		},
+		"acct:other@example.com": {
+			Subject: "acct:other@example.com",
+			Properties: map[string]string{
+				"http://webfinger.net/rel/name": "Jane Doe",
+			},
+		},
+	}
+
+	loadedAt := time.Date(2023, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+	h := handler.WebfingerHandler(fingers,
+		handler.WithLastModified(loadedAt),
+		handler.WithMaxAge(time.Hour),
+		handler.WithResourceMaxAge("acct:other@example.com", time.Minute),
+	)
+
+	serve := func(query string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?"+query, http.NoBody)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w
+	}
+
+	first := serve("resource=acct:user@example.com", nil)
+	etag := first.Header().Get("ETag")
+
+	t.Run("sends validators", func(t *testing.T) {
+		t.Parallel()
+
+		if etag == "" {
+			t.Error("expected an ETag")
+		}
+
+		if got := first.Header().Get("Last-Modified"); got != loadedAt.Format(http.TimeFormat) {
+			t.Errorf("expected Last-Modified %s, got %s", loadedAt.Format(http.TimeFormat), got)
+		}
+
+		if got := first.Header().Get("Cache-Control"); got != "max-age=3600" {
+			t.Errorf("expected Cache-Control max-age=3600, got %s", got)
+		}
+	})
+
+	t.Run("etag is stable", func(t *testing.T) {
+		t.Parallel()
+
+		if got := serve("resource=acct:user@example.com", nil).Header().Get("ETag"); got != etag {
+			t.Errorf("expected ETag %s, got %s", etag, got)
+		}
+	})
+
+	t.Run("etag changes with rel filter", func(t *testing.T) {
+		t.Parallel()
+
+		got := serve("resource=acct:user@example.com&rel=http://webfinger.net/rel/avatar", nil).Header().Get("ETag")
+		if got == "" || got == etag {
+			t.Errorf("expected a different ETag than %s, got %s", etag, got)
+		}
+	})
+
+	t.Run("uses per resource max-age", func(t *testing.T) {
+		t.Parallel()
+
+		if got := serve("resource=acct:other@example.com", nil).Header().Get("Cache-Control"); got != "max-age=60" {
+			t.Errorf("expected Cache-Control max-age=60, got %s", got)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		wantCode int
+	}{
+		{
+			name:     "matching If-None-Match",
+			headers:  map[string]string{"If-None-Match": etag},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:     "matching weak If-None-Match in a list",
+			headers:  map[string]string{"If-None-Match": `"other", W/` + etag},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:     "wildcard If-None-Match",
+			headers:  map[string]string{"If-None-Match": "*"},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:     "stale If-None-Match",
+			headers:  map[string]string{"If-None-Match": `"stale"`},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "If-Modified-Since after load",
+			headers:  map[string]string{"If-Modified-Since": loadedAt.Add(time.Hour).Format(http.TimeFormat)},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:     "If-Modified-Since at load",
+			headers:  map[string]string{"If-Modified-Since": loadedAt.Format(http.TimeFormat)},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:     "If-Modified-Since before load",
+			headers:  map[string]string{"If-Modified-Since": loadedAt.Add(-time.Hour).Format(http.TimeFormat)},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "invalid If-Modified-Since",
+			headers:  map[string]string{"If-Modified-Since": "yesterday"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "If-None-Match takes precedence",
+			headers: map[string]string{
+				"If-None-Match":     `"stale"`,
+				"If-Modified-Since": loadedAt.Add(time.Hour).Format(http.TimeFormat),
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := serve("resource=acct:user@example.com", tc.headers)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("expected status code %d, got %d", tc.wantCode, w.Code)
+			}
+
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("expected ETag %s, got %s", etag, w.Header().Get("ETag"))
+			}
+
+			if tc.wantCode == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("expected empty body, got %q", w.Body.String())
+			}
+		})
+	}
+}
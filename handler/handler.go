@@ -1,26 +1,253 @@
 package handler
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 
+	"git.maronato.dev/maronato/finger/internal/negotiate"
 	"git.maronato.dev/maronato/finger/webfingers"
 )
 
-func WebfingerHandler(fingers webfingers.WebFingers) http.Handler {
+// maxFilteredVariants is the maximum number of rel-filtered responses
+// cached per resource. Variants past this limit are encoded on demand.
+const maxFilteredVariants = 64
+
+// Outcome is the result of a webfinger lookup.
+type Outcome string
+
+const (
+	// OutcomeHit means the resource was found.
+	OutcomeHit Outcome = "hit"
+	// OutcomeMiss means the resource was not found.
+	OutcomeMiss Outcome = "miss"
+)
+
+// Lookup describes a webfinger lookup made by a client.
+type Lookup struct {
+	Resource string
+	Rels     []string
+	Outcome  Outcome
+}
+
+// Observer is called with the outcome of every lookup.
+type Observer func(r *http.Request, lookup Lookup)
+
+// Pre-built header values. They're copied into every response, since
+// middlewares may modify the header slices in place.
+var (
+	jrdContentType      = []string{"application/jrd+json"} //nolint:gochecknoglobals // Copied into every response
+	gzipContentEncoding = []string{"gzip"}                 //nolint:gochecknoglobals // Copied into every response
+	varyAcceptEncoding  = []string{"Accept-Encoding"}      //nolint:gochecknoglobals // Copied into every response
+)
+
+// payload is a pre-serialized JRD document ready to be written to clients.
+type payload struct {
+	body          []byte
+	contentLength []string
+	etag          []string
+
+	// gzipped is the compressed variant of the payload, if any.
+	gzipped *payload
+}
+
+func newPayload(finger *webfingers.WebFinger, gzipMinSize int) (*payload, error) {
+	body, err := json.Marshal(finger)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding json: %w", err)
+	}
+
+	// Keep the trailing newline json.Encoder used to write
+	body = append(body, '\n')
+
+	p := &payload{
+		body:          body,
+		contentLength: []string{strconv.Itoa(len(body))},
+		etag:          []string{computeETag(body)},
+	}
+
+	// Pre-compress large payloads
+	if gzipMinSize > 0 && len(body) >= gzipMinSize {
+		buf := &bytes.Buffer{}
+		gz, _ := gzip.NewWriterLevel(buf, gzip.BestCompression)
+
+		if _, err := gz.Write(body); err != nil {
+			return nil, fmt.Errorf("error compressing json: %w", err)
+		}
+
+		if err := gz.Close(); err != nil {
+			return nil, fmt.Errorf("error compressing json: %w", err)
+		}
+
+		gzipped := buf.Bytes()
+
+		p.gzipped = &payload{
+			body:          gzipped,
+			contentLength: []string{strconv.Itoa(len(gzipped))},
+			etag:          []string{computeETag(gzipped)},
+		}
+	}
+
+	return p, nil
+}
+
+// resource holds the cached responses for a single webfinger.
+type resource struct {
+	finger       *webfingers.WebFinger
+	full         *payload
+	cacheControl []string
+	gzipMinSize  int
+
+	mu       sync.RWMutex
+	filtered map[string]*payload
+}
+
+// filter returns the payload containing only the links whose rel is in rels.
+func (res *resource) filter(rels []string) (*payload, error) {
+	// Build a key out of the indexes of the links that match the rels.
+	// Only existing links take part in the key, so arbitrary rels sent
+	// by clients can't grow the cache.
+	key := make([]byte, 0, len(res.finger.Links))
+	matched := 0
+
+	for i, link := range res.finger.Links {
+		for _, rel := range rels {
+			if link.Rel == rel {
+				key = strconv.AppendInt(key, int64(i), 10)
+				key = append(key, ',')
+				matched++
+
+				break
+			}
+		}
+	}
+
+	// Every link matched, so the full response can be used
+	if matched == len(res.finger.Links) {
+		return res.full, nil
+	}
+
+	res.mu.RLock()
+	p, ok := res.filtered[string(key)]
+	res.mu.RUnlock()
+
+	if ok {
+		return p, nil
+	}
+
+	// Create the filtered webfinger
+	finger := &webfingers.WebFinger{
+		Subject:    res.finger.Subject,
+		Properties: res.finger.Properties,
+	}
+
+	if matched > 0 {
+		finger.Links = make([]webfingers.Link, 0, matched)
+
+		for _, link := range res.finger.Links {
+			for _, rel := range rels {
+				if link.Rel == rel {
+					finger.Links = append(finger.Links, link)
+
+					break
+				}
+			}
+		}
+	}
+
+	p, err := newPayload(finger, res.gzipMinSize)
+	if err != nil {
+		return nil, err
+	}
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	if len(res.filtered) < maxFilteredVariants {
+		res.filtered[string(key)] = p
+	}
+
+	return p, nil
+}
+
+// parseQuery extracts the resource and rel params from a raw query string.
+// It mirrors url.Values.Get for the resource and only allocates when
+// values need unescaping or rels are present.
+func parseQuery(rawQuery string) (resource string, rels []string) {
+	foundResource := false
+
+	for rawQuery != "" {
+		var pair string
+
+		pair, rawQuery, _ = strings.Cut(rawQuery, "&")
+
+		key, value, _ := strings.Cut(pair, "=")
+		if key != "resource" && key != "rel" {
+			continue
+		}
+
+		if strings.ContainsAny(value, "%+") {
+			unescaped, err := url.QueryUnescape(value)
+			if err != nil {
+				continue
+			}
+
+			value = unescaped
+		}
+
+		if key == "rel" {
+			rels = append(rels, value)
+		} else if !foundResource {
+			resource = value
+			foundResource = true
+		}
+	}
+
+	return resource, rels
+}
+
+func WebfingerHandler(fingers webfingers.WebFingers, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	lastModified := []string{o.lastModified.UTC().Format(http.TimeFormat)}
+
+	// Serialize every webfinger ahead of time, since they never change
+	resources := make(map[string]*resource, len(fingers))
+
+	for key, finger := range fingers {
+		full, err := newPayload(finger, o.gzipMinSize)
+		if err != nil {
+			// Webfingers only contain strings, so this should never happen
+			panic(err)
+		}
+
+		resources[key] = &resource{
+			finger:       finger,
+			full:         full,
+			cacheControl: o.cacheControl(key),
+			gzipMinSize:  o.gzipMinSize,
+			filtered:     make(map[string]*payload),
+		}
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Only handle GET requests
-		if r.Method != http.MethodGet {
+		// Only handle GET and HEAD requests
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
 			return
 		}
 
 		// Get the query params
-		q := r.URL.Query()
+		resource, rels := parseQuery(r.URL.RawQuery)
 
 		// Get the resource
-		resource := q.Get("resource")
 		if resource == "" {
 			http.Error(w, "No resource provided", http.StatusBadRequest)
 
@@ -28,21 +255,77 @@ func WebfingerHandler(fingers webfingers.WebFingers) http.Handler {
 		}
 
 		// Get and validate resource
-		finger, ok := fingers[resource]
+		res, ok := resources[resource]
+
+		if len(o.observers) > 0 {
+			lookup := Lookup{Resource: resource, Rels: rels, Outcome: OutcomeHit}
+			if !ok {
+				lookup.Outcome = OutcomeMiss
+			}
+
+			for _, observe := range o.observers {
+				observe(r, lookup)
+			}
+		}
+
 		if !ok {
 			http.Error(w, "Resource not found", http.StatusNotFound)
 
 			return
 		}
 
-		// Set the content type
-		w.Header().Set("Content-Type", "application/jrd+json")
+		// Apply the rel filter, if any
+		p := res.full
+
+		if len(rels) > 0 {
+			var err error
+
+			p, err = res.filter(rels)
+			if err != nil {
+				http.Error(w, "Error encoding json", http.StatusInternalServerError)
+
+				return
+			}
+		}
+
+		h := w.Header()
 
-		// Write the response
-		if err := json.NewEncoder(w).Encode(finger); err != nil {
-			http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		// Use the pre-compressed payload if the client supports it
+		if o.gzipMinSize > 0 {
+			if _, ok := h["Vary"]; !ok {
+				h["Vary"] = slices.Clone(varyAcceptEncoding)
+			}
+
+			if p.gzipped != nil && negotiate.AcceptsEncoding(r, "gzip") {
+				p = p.gzipped
+				h["Content-Encoding"] = slices.Clone(gzipContentEncoding)
+			}
+		}
+
+		// Set the cache validators
+		h["Etag"] = slices.Clone(p.etag)
+		h["Last-Modified"] = slices.Clone(lastModified)
+
+		if res.cacheControl != nil {
+			h["Cache-Control"] = slices.Clone(res.cacheControl)
+		}
+
+		// Let the client reuse its cached copy if it is still fresh
+		if notModified(r, p.etag[0], o.lastModified) {
+			w.WriteHeader(http.StatusNotModified)
 
 			return
 		}
+
+		// Set the content type and length
+		h["Content-Type"] = slices.Clone(jrdContentType)
+		h["Content-Length"] = slices.Clone(p.contentLength)
+
+		// Write the response, without a body for HEAD requests
+		w.WriteHeader(http.StatusOK)
+
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(p.body)
+		}
 	})
 }
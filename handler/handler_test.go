@@ -1,12 +1,15 @@
 package handler_test
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -121,6 +124,11 @@ func TestWebfingerHandler(t *testing.T) {
 					t.Errorf("expected content type %s, got %s", "application/jrd+json", w.Header().Get("Content-Type"))
 				}
 
+				// Check the content length
+				if w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) {
+					t.Errorf("expected content length %d, got %s", w.Body.Len(), w.Header().Get("Content-Length"))
+				}
+
 				fingerWant := fingers[tc.resource]
 				fingerGot := &webfingers.WebFinger{}
 
@@ -148,11 +156,357 @@ func TestWebfingerHandler(t *testing.T) {
 	}
 }
 
-func BenchmarkWebfingerHandler(b *testing.B) {
+func TestWebfingerHandler_RelFilter(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {
+			Subject: "acct:user@example.com",
+			Links: []webfingers.Link{
+				{
+					Rel:  "http://webfinger.net/rel/profile-page",
+					Href: "https://example.com/user",
+				},
+				{
+					Rel:  "http://webfinger.net/rel/avatar",
+					Href: "https://example.com/avatar",
+				},
+				{
+					Rel:  "http://openid.net/specs/connect/1.0/issuer",
+					Href: "https://sso.example.com/",
+				},
+			},
+			Properties: map[string]string{
+				"http://webfinger.net/rel/name": "John Doe",
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantLinks []string
+	}{
+		{
+			name:  "no rel returns all links",
+			query: "resource=acct:user@example.com",
+			wantLinks: []string{
+				"http://webfinger.net/rel/profile-page",
+				"http://webfinger.net/rel/avatar",
+				"http://openid.net/specs/connect/1.0/issuer",
+			},
+		},
+		{
+			name:      "single rel",
+			query:     "resource=acct:user@example.com&rel=http://webfinger.net/rel/avatar",
+			wantLinks: []string{"http://webfinger.net/rel/avatar"},
+		},
+		{
+			name:  "multiple rels keep link order",
+			query: "rel=http%3A%2F%2Fopenid.net%2Fspecs%2Fconnect%2F1.0%2Fissuer&resource=acct%3Auser%40example.com&rel=http://webfinger.net/rel/profile-page",
+			wantLinks: []string{
+				"http://webfinger.net/rel/profile-page",
+				"http://openid.net/specs/connect/1.0/issuer",
+			},
+		},
+		{
+			name:      "unknown rel returns no links",
+			query:     "resource=acct:user@example.com&rel=unknown",
+			wantLinks: []string{},
+		},
+	}
+
+	h := handler.WebfingerHandler(fingers)
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Run it twice so the cached variant is also checked
+			for i := 0; i < 2; i++ {
+				r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?"+tc.query, http.NoBody)
+				w := httptest.NewRecorder()
+
+				h.ServeHTTP(w, r)
+
+				if w.Code != http.StatusOK {
+					t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+				}
+
+				fingerGot := &webfingers.WebFinger{}
+				if err := json.NewDecoder(w.Body).Decode(fingerGot); err != nil {
+					t.Fatalf("error decoding json: %v", err)
+				}
+
+				gotLinks := []string{}
+				for _, link := range fingerGot.Links {
+					gotLinks = append(gotLinks, link.Rel)
+				}
+
+				if !reflect.DeepEqual(gotLinks, tc.wantLinks) {
+					t.Errorf("expected links %v, got %v", tc.wantLinks, gotLinks)
+				}
+
+				if fingerGot.Properties["http://webfinger.net/rel/name"] != "John Doe" {
+					t.Errorf("expected properties to be kept, got %v", fingerGot.Properties)
+				}
+			}
+		})
+	}
+}
+
+func TestWebfingerHandler_Gzip(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {
+			Subject: "acct:user@example.com",
+			Properties: map[string]string{
+				"http://webfinger.net/rel/name": strings.Repeat("John Doe ", 50),
+			},
+		},
+		"acct:other@example.com": {
+			Subject: "acct:other@example.com",
+		},
+	}
+
+	h := handler.WebfingerHandler(fingers, handler.WithGzip(100))
+
+	tests := []struct {
+		name           string
+		resource       string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{
+			name:           "compresses large responses",
+			resource:       "acct:user@example.com",
+			acceptEncoding: "gzip, br",
+			wantGzip:       true,
+		},
+		{
+			name:     "skips clients without gzip",
+			resource: "acct:user@example.com",
+			wantGzip: false,
+		},
+		{
+			name:           "skips small responses",
+			resource:       "acct:other@example.com",
+			acceptEncoding: "gzip",
+			wantGzip:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource="+tc.resource, http.NoBody)
+			if tc.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+			}
+
+			if w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) {
+				t.Errorf("expected content length %d, got %s", w.Body.Len(), w.Header().Get("Content-Length"))
+			}
+
+			isGzip := w.Header().Get("Content-Encoding") == "gzip"
+			if isGzip != tc.wantGzip {
+				t.Fatalf("expected gzip %v, got Content-Encoding %q", tc.wantGzip, w.Header().Get("Content-Encoding"))
+			}
+
+			var body io.Reader = w.Body
+
+			if isGzip {
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("error reading gzip: %v", err)
+				}
+
+				body = gz
+			}
+
+			fingerGot := &webfingers.WebFinger{}
+			if err := json.NewDecoder(body).Decode(fingerGot); err != nil {
+				t.Fatalf("error decoding json: %v", err)
+			}
+
+			if !reflect.DeepEqual(fingerGot, fingers[tc.resource]) {
+				t.Errorf("expected body %v, got %v", fingers[tc.resource], fingerGot)
+			}
+		})
+	}
+}
+
+func TestWebfingerHandler_Observer(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {
+			Subject: "acct:user@example.com",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []handler.Lookup
+	}{
+		{
+			name:  "hit",
+			query: "resource=acct:user@example.com",
+			want:  []handler.Lookup{{Resource: "acct:user@example.com", Outcome: handler.OutcomeHit}},
+		},
+		{
+			name:  "hit with rels",
+			query: "resource=acct:user@example.com&rel=avatar&rel=profile",
+			want: []handler.Lookup{{
+				Resource: "acct:user@example.com",
+				Rels:     []string{"avatar", "profile"},
+				Outcome:  handler.OutcomeHit,
+			}},
+		},
+		{
+			name:  "miss",
+			query: "resource=acct:other@example.com",
+			want:  []handler.Lookup{{Resource: "acct:other@example.com", Outcome: handler.OutcomeMiss}},
+		},
+		{
+			name:  "no resource",
+			query: "",
+			want:  []handler.Lookup{},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := []handler.Lookup{}
+
+			h := handler.WebfingerHandler(fingers, handler.WithObserver(func(_ *http.Request, lookup handler.Lookup) {
+				got = append(got, lookup)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?"+tc.query, http.NoBody)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected lookups %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWebfingerHandler_Head(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {
+			Subject: "acct:user@example.com",
+		},
+	}
+
+	h := handler.WebfingerHandler(fingers)
+
+	get := httptest.NewRecorder()
+	h.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:user@example.com", http.NoBody))
+
+	head := httptest.NewRecorder()
+	h.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/.well-known/webfinger?resource=acct:user@example.com", http.NoBody))
+
+	if head.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, head.Code)
+	}
+
+	if head.Body.Len() != 0 {
+		t.Errorf("expected no body, got %q", head.Body.String())
+	}
+
+	// Headers match the GET response, including its length
+	if !reflect.DeepEqual(head.Header(), get.Header()) {
+		t.Errorf("expected headers %v, got %v", get.Header(), head.Header())
+	}
+}
+
+func TestWebfingerHandler_HeaderCopies(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {
+			Subject: "acct:user@example.com",
+			Properties: map[string]string{
+				"http://webfinger.net/rel/name": strings.Repeat("John Doe ", 50),
+			},
+		},
+	}
+
+	h := handler.WebfingerHandler(fingers, handler.WithGzip(100))
+
+	serve := func() http.Header {
+		r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:user@example.com", http.NoBody)
+		r.Header.Set("Accept-Encoding", "gzip")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w.Header()
+	}
+
+	want := serve().Clone()
+
+	// Modify the header values in place, like a middleware could
+	for _, values := range serve() {
+		values[0] = "modified"
+	}
+
+	if got := serve(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected headers %v, got %v", want, got)
+	}
+}
+
+// discardWriter is a reusable http.ResponseWriter that discards the
+// response, so benchmarks only measure the handler.
+type discardWriter struct {
+	header http.Header
+	code   int
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardWriter) WriteHeader(code int) {
+	w.code = code
+}
+
+func newBenchFingers(b *testing.B) webfingers.WebFingers {
+	b.Helper()
+
 	fingers, err := webfingers.NewWebFingers(
 		webfingers.Resources{
 			"user@example.com": {
-				"prop1": "value1",
+				"name":    "Example User",
+				"avatar":  "https://example.com/avatar",
+				"profile": "https://example.com/user",
+				"openid":  "https://sso.example.com/",
 			},
 		},
 		nil,
@@ -161,16 +515,100 @@ func BenchmarkWebfingerHandler(b *testing.B) {
 		b.Fatal(err)
 	}
 
+	return fingers
+}
+
+func BenchmarkWebfingerHandler(b *testing.B) {
+	fingers := newBenchFingers(b)
+
 	h := handler.WebfingerHandler(fingers)
 	r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:user@example.com", http.NoBody)
+	w := &discardWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.code != http.StatusOK {
+			b.Errorf("expected status code %d, got %d", http.StatusOK, w.code)
+		}
+	}
+}
+
+func BenchmarkWebfingerHandler_Gzip(b *testing.B) {
+	fingers := newBenchFingers(b)
+
+	h := handler.WebfingerHandler(fingers, handler.WithGzip(1))
+	r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:user@example.com", http.NoBody)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	w := &discardWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, r)
+
+		if w.code != http.StatusOK {
+			b.Errorf("expected status code %d, got %d", http.StatusOK, w.code)
+		}
+	}
+}
+
+func BenchmarkWebfingerHandler_RelFilter(b *testing.B) {
+	fingers := newBenchFingers(b)
+
+	h := handler.WebfingerHandler(fingers)
+	r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:user@example.com&rel=avatar", http.NoBody)
+	w := &discardWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, r)
+
+		if w.code != http.StatusOK {
+			b.Errorf("expected status code %d, got %d", http.StatusOK, w.code)
+		}
+	}
+}
+
+// BenchmarkEncodePerRequest measures the previous strategy of encoding
+// the webfinger on every request, as a baseline for the cached handler.
+func BenchmarkEncodePerRequest(b *testing.B) {
+	fingers := newBenchFingers(b)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		finger, ok := fingers[r.URL.Query().Get("resource")]
+		if !ok {
+			http.Error(w, "Resource not found", http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/jrd+json")
+
+		if err := json.NewEncoder(w).Encode(finger); err != nil {
+			http.Error(w, "Error encoding json", http.StatusInternalServerError)
+		}
+	})
+	r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:user@example.com", http.NoBody)
+	w := &discardWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		w.code = 0
 
 		h.ServeHTTP(w, r)
 
-		if w.Code != http.StatusOK {
-			b.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		if w.code != 0 && w.code != http.StatusOK {
+			b.Errorf("expected status code %d, got %d", http.StatusOK, w.code)
 		}
 	}
 }
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+)
+
+// Option configures the webfinger handler.
+type Option func(*options)
+
+type options struct {
+	lastModified   time.Time
+	maxAge         time.Duration
+	resourceMaxAge map[string]time.Duration
+	gzipMinSize    int
+	observers      []Observer
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		lastModified:   time.Now(),
+		resourceMaxAge: make(map[string]time.Duration),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithLastModified sets the time reported in the Last-Modified header.
+// It defaults to the time the handler was created.
+func WithLastModified(t time.Time) Option {
+	return func(o *options) {
+		o.lastModified = t
+	}
+}
+
+// WithMaxAge sets the max-age sent in the Cache-Control header of every
+// response. No Cache-Control header is sent if it is zero.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithResourceMaxAge overrides the Cache-Control max-age of a single
+// resource. The resource must be the subject as it is served, including
+// the acct: prefix of email addresses.
+func WithResourceMaxAge(resource string, maxAge time.Duration) Option {
+	return func(o *options) {
+		o.resourceMaxAge[resource] = maxAge
+	}
+}
+
+// WithGzip pre-compresses responses of at least minSize bytes, which are
+// then served to clients that accept gzip. Compression is disabled if
+// minSize is zero.
+func WithGzip(minSize int) Option {
+	return func(o *options) {
+		o.gzipMinSize = minSize
+	}
+}
+
+// WithObserver registers a function that is called with the outcome of
+// every lookup. It can be given multiple times.
+func WithObserver(fn Observer) Option {
+	return func(o *options) {
+		o.observers = append(o.observers, fn)
+	}
+}
+
+// cacheControl returns the Cache-Control header value for a resource.
+func (o *options) cacheControl(resource string) []string {
+	maxAge, ok := o.resourceMaxAge[resource]
+	if !ok {
+		maxAge = o.maxAge
+	}
+
+	if maxAge <= 0 {
+		return nil
+	}
+
+	return []string{"max-age=" + strconv.Itoa(int(maxAge.Seconds()))}
+}
@@ -0,0 +1,487 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"git.maronato.dev/maronato/finger/internal/audit"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+// maxBodySize is the maximum size of request bodies.
+const maxBodySize = 1 << 20
+
+// Actions of the changes made through the API, as recorded in the audit log.
+const (
+	actionCreateResource = "create_resource"
+	actionUpdateResource = "update_resource"
+	actionDeleteResource = "delete_resource"
+	actionCreateURN      = "create_urn"
+	actionUpdateURN      = "update_urn"
+	actionDeleteURN      = "delete_urn"
+)
+
+var (
+	// ErrNotFound is returned when the resource or URN alias doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when the resource or URN alias already exists.
+	ErrConflict = errors.New("already exists")
+	// ErrInvalid is returned when a change would make the webfingers invalid.
+	ErrInvalid = errors.New("invalid")
+)
+
+// SourceFunc loads the resources and URN aliases the webfingers are made of.
+type SourceFunc func(ctx context.Context) (webfingers.Resources, webfingers.URNAliases, error)
+
+// SaveFunc saves the resources and URN aliases back to their source.
+type SaveFunc func(ctx context.Context, resources webfingers.Resources, urnAliases webfingers.URNAliases) error
+
+// Option configures the API.
+type Option func(*API)
+
+// WithSave makes the API save every change with save before serving it,
+// so changes outlive reloads and restarts.
+func WithSave(save SaveFunc) Option {
+	return func(a *API) {
+		a.save = save
+	}
+}
+
+// Resource is a resource and the fields of its webfinger, as in the fingers
+// file.
+type Resource struct {
+	Subject string            `json:"subject"`
+	Fields  map[string]string `json:"fields"`
+}
+
+// URN is a URN alias, as in the URNs file.
+type URN struct {
+	Name string `json:"name"`
+	URN  string `json:"urn"`
+}
+
+// API manages the resources and URN aliases being served at runtime.
+type API struct {
+	source SourceFunc
+	save   SaveFunc
+
+	// mu serializes changes so none of them are lost.
+	mu         sync.Mutex
+	resources  webfingers.Resources
+	urnAliases webfingers.URNAliases
+}
+
+// New creates an API for the webfingers loaded from source.
+func New(source SourceFunc, opts ...Option) *API {
+	a := &API{
+		source:     source,
+		resources:  webfingers.Resources{},
+		urnAliases: webfingers.URNAliases{},
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Load loads the source and returns its webfingers. It's meant to be the
+// load function of the store, so reloads replace the changes made through
+// the API with the source unless they were saved.
+func (a *API) Load(ctx context.Context) (webfingers.WebFingers, error) {
+	resources, urnAliases, err := a.source(ctx)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapped by the store
+	}
+
+	fingers, err := webfingers.NewWebFingers(resources, urnAliases)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing raw fingers: %w", err)
+	}
+
+	// Keep empty maps so changes can be added to them
+	if resources == nil {
+		resources = webfingers.Resources{}
+	}
+
+	if urnAliases == nil {
+		urnAliases = webfingers.URNAliases{}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.resources = resources
+	a.urnAliases = urnAliases
+
+	return fingers, nil
+}
+
+// update applies fn to a copy of the resources and URN aliases and, if
+// the result is valid, saves it and swaps the new webfingers into the store
+// as the action. Reloads of the store wait for it, so the store always
+// serves the resources of the API.
+func (a *API) update(
+	ctx context.Context,
+	s *store.Store,
+	action string,
+	fn func(webfingers.Resources, webfingers.URNAliases) error,
+) error {
+	return s.Update(audit.WithAction(ctx, action), func() (webfingers.WebFingers, error) { //nolint:wrapcheck // Errors of fn
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		resources := make(webfingers.Resources, len(a.resources))
+		for k, v := range a.resources {
+			resources[k] = maps.Clone(v)
+		}
+
+		urnAliases := maps.Clone(a.urnAliases)
+
+		if err := fn(resources, urnAliases); err != nil {
+			return nil, err
+		}
+
+		// Use the same rules as the files
+		if err := fingerreader.ValidateURNs(urnAliases); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+
+		fingers, err := webfingers.NewWebFingers(resources, urnAliases)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+
+		// Only serve changes that were saved
+		if a.save != nil {
+			if err := a.save(ctx, resources, urnAliases); err != nil {
+				return nil, fmt.Errorf("error saving changes: %w", err)
+			}
+		}
+
+		a.resources = resources
+		a.urnAliases = urnAliases
+
+		return fingers, nil
+	})
+}
+
+// Handler serves the API for the webfingers in the store. Every request
+// must have one of the tokens.
+func (a *API) Handler(s *store.Store, tokens []Token) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/admin/resources", a.resourcesHandler(s))
+	mux.Handle("/admin/urns", a.urnsHandler(s))
+
+	return Authenticate(tokens)(mux)
+}
+
+// resourcesHandler lists resources, or gets, updates and deletes the one
+// in the resource query param, and creates new ones.
+func (a *API) resourcesHandler(s *store.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := log.FromContext(ctx)
+		resource := r.URL.Query().Get("resource")
+
+		switch {
+		case r.Method == http.MethodGet && resource == "":
+			a.mu.Lock()
+			list := make([]Resource, 0, len(a.resources))
+
+			for k, v := range a.resources {
+				list = append(list, newResource(k, v))
+			}
+			a.mu.Unlock()
+
+			sort.Slice(list, func(i, j int) bool {
+				return list[i].Subject < list[j].Subject
+			})
+
+			writeJSON(w, http.StatusOK, list)
+
+		case r.Method == http.MethodGet:
+			a.mu.Lock()
+			key, ok := a.resources.Find(resource)
+			fields := a.resources[key]
+			a.mu.Unlock()
+
+			if !ok {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("resource %s %s", resource, ErrNotFound))
+
+				return
+			}
+
+			writeJSON(w, http.StatusOK, newResource(key, fields))
+
+		case r.Method == http.MethodPost && resource == "":
+			var res Resource
+			if !readJSON(w, r, &res) {
+				return
+			}
+
+			err := a.update(ctx, s, actionCreateResource, func(resources webfingers.Resources, _ webfingers.URNAliases) error {
+				if res.Subject == "" {
+					return fmt.Errorf("%w: subject is empty", ErrInvalid)
+				}
+
+				if _, ok := resources.Find(res.Subject); ok {
+					return fmt.Errorf("resource %s %w", res.Subject, ErrConflict)
+				}
+
+				resources[res.Subject] = res.Fields
+
+				return nil
+			})
+			if err != nil {
+				writeUpdateError(w, err)
+
+				return
+			}
+
+			l.Info("Created resource", slog.String("subject", res.Subject), actorAttr(ctx))
+			writeJSON(w, http.StatusCreated, newResource(res.Subject, res.Fields))
+
+		case r.Method == http.MethodPut && resource != "":
+			var res Resource
+			if !readJSON(w, r, &res) {
+				return
+			}
+
+			var key string
+
+			err := a.update(ctx, s, actionUpdateResource, func(resources webfingers.Resources, _ webfingers.URNAliases) error {
+				var ok bool
+				if key, ok = resources.Find(resource); !ok {
+					return fmt.Errorf("resource %s %w", resource, ErrNotFound)
+				}
+
+				resources[key] = res.Fields
+
+				return nil
+			})
+			if err != nil {
+				writeUpdateError(w, err)
+
+				return
+			}
+
+			l.Info("Updated resource", slog.String("subject", resource), actorAttr(ctx))
+			writeJSON(w, http.StatusOK, newResource(key, res.Fields))
+
+		case r.Method == http.MethodDelete && resource != "":
+			err := a.update(ctx, s, actionDeleteResource, func(resources webfingers.Resources, _ webfingers.URNAliases) error {
+				key, ok := resources.Find(resource)
+				if !ok {
+					return fmt.Errorf("resource %s %w", resource, ErrNotFound)
+				}
+
+				delete(resources, key)
+
+				return nil
+			})
+			if err != nil {
+				writeUpdateError(w, err)
+
+				return
+			}
+
+			l.Info("Deleted resource", slog.String("subject", resource), actorAttr(ctx))
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			writeMethodNotAllowed(w, resource != "")
+		}
+	})
+}
+
+// urnsHandler lists URN aliases, or gets, updates and deletes the one in
+// the name query param, and creates new ones.
+func (a *API) urnsHandler(s *store.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := log.FromContext(ctx)
+		name := r.URL.Query().Get("name")
+
+		switch {
+		case r.Method == http.MethodGet && name == "":
+			a.mu.Lock()
+			list := make([]URN, 0, len(a.urnAliases))
+
+			for k, v := range a.urnAliases {
+				list = append(list, URN{Name: k, URN: v})
+			}
+			a.mu.Unlock()
+
+			sort.Slice(list, func(i, j int) bool {
+				return list[i].Name < list[j].Name
+			})
+
+			writeJSON(w, http.StatusOK, list)
+
+		case r.Method == http.MethodGet:
+			a.mu.Lock()
+			urn, ok := a.urnAliases[name]
+			a.mu.Unlock()
+
+			if !ok {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("URN alias %s %s", name, ErrNotFound))
+
+				return
+			}
+
+			writeJSON(w, http.StatusOK, URN{Name: name, URN: urn})
+
+		case (r.Method == http.MethodPost && name == "") || (r.Method == http.MethodPut && name != ""):
+			var urn URN
+			if !readJSON(w, r, &urn) {
+				return
+			}
+
+			create := r.Method == http.MethodPost
+			action := actionCreateURN
+
+			if !create {
+				urn.Name = name
+				action = actionUpdateURN
+			}
+
+			err := a.update(ctx, s, action, func(_ webfingers.Resources, urnAliases webfingers.URNAliases) error {
+				_, exists := urnAliases[urn.Name]
+
+				switch {
+				case urn.Name == "":
+					return fmt.Errorf("%w: name is empty", ErrInvalid)
+				case create && exists:
+					return fmt.Errorf("URN alias %s %w", urn.Name, ErrConflict)
+				case !create && !exists:
+					return fmt.Errorf("URN alias %s %w", urn.Name, ErrNotFound)
+				}
+
+				urnAliases[urn.Name] = urn.URN
+
+				return nil
+			})
+			if err != nil {
+				writeUpdateError(w, err)
+
+				return
+			}
+
+			code := http.StatusOK
+			if create {
+				code = http.StatusCreated
+			}
+
+			l.Info("Saved URN alias", slog.String("name", urn.Name), slog.String("urn", urn.URN), actorAttr(ctx))
+			writeJSON(w, code, urn)
+
+		case r.Method == http.MethodDelete && name != "":
+			err := a.update(ctx, s, actionDeleteURN, func(_ webfingers.Resources, urnAliases webfingers.URNAliases) error {
+				if _, ok := urnAliases[name]; !ok {
+					return fmt.Errorf("URN alias %s %w", name, ErrNotFound)
+				}
+
+				delete(urnAliases, name)
+
+				return nil
+			})
+			if err != nil {
+				writeUpdateError(w, err)
+
+				return
+			}
+
+			l.Info("Deleted URN alias", slog.String("name", name), actorAttr(ctx))
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			writeMethodNotAllowed(w, name != "")
+		}
+	})
+}
+
+func newResource(key string, fields map[string]string) Resource {
+	if fields == nil {
+		fields = map[string]string{}
+	}
+
+	// Show the subject the way it's served
+	subject, err := webfingers.ParseSubject(key)
+	if err != nil {
+		subject = key
+	}
+
+	return Resource{Subject: subject, Fields: fields}
+}
+
+func actorAttr(ctx context.Context) slog.Attr {
+	actor, _ := ActorFromContext(ctx)
+
+	return slog.String("actor", actor)
+}
+
+// readJSON decodes the request body into v, or writes a 400 and returns
+// false.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %s", err))
+
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// writeUpdateError writes the status code that matches the error.
+func writeUpdateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrConflict), errors.Is(err, fingerreader.ErrChanged):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalid):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// writeMethodNotAllowed writes a 405 with the methods allowed on the list
+// or on a single item.
+func writeMethodNotAllowed(w http.ResponseWriter, item bool) {
+	allow := []string{http.MethodGet, http.MethodPost}
+	if item {
+		allow = []string{http.MethodGet, http.MethodPut, http.MethodDelete}
+	}
+
+	w.Header().Set("Allow", strings.Join(allow, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+}
@@ -0,0 +1,340 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/admin"
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+const token = "secret"
+
+// newAPI creates an API serving the resources, and the store it updates.
+func newAPI(t *testing.T, resources webfingers.Resources, urnAliases webfingers.URNAliases) (http.Handler, *store.Store) {
+	t.Helper()
+
+	api := admin.New(func(_ context.Context) (webfingers.Resources, webfingers.URNAliases, error) {
+		return resources, urnAliases, nil
+	})
+
+	fingers, err := api.Load(context.Background())
+	if err != nil {
+		t.Fatalf("error loading: %v", err)
+	}
+
+	s := store.New(fingers, api.Load)
+
+	return api.Handler(s, []admin.Token{{Name: "ci", Value: token}}), s
+}
+
+func do(t *testing.T, h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	ctx := log.WithLogger(context.Background(), log.NewLogger(&strings.Builder{}, config.NewConfig()))
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body)).WithContext(ctx)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestAPI_Resources(t *testing.T) {
+	t.Parallel()
+
+	h, s := newAPI(t, webfingers.Resources{
+		"user@example.com": {"name": "User"},
+	}, webfingers.URNAliases{
+		"avatar": "http://webfinger.net/rel/avatar",
+	})
+
+	steps := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		wantCode int
+	}{
+		{"list", http.MethodGet, "/admin/resources", "", http.StatusOK},
+		{"get with acct", http.MethodGet, "/admin/resources?resource=acct:user@example.com", "", http.StatusOK},
+		{"get missing", http.MethodGet, "/admin/resources?resource=acct:nobody@example.com", "", http.StatusNotFound},
+		{"create", http.MethodPost, "/admin/resources", `{"subject":"bob@example.com","fields":{"avatar":"https://example.com/bob.png"}}`, http.StatusCreated},
+		{"create existing", http.MethodPost, "/admin/resources", `{"subject":"acct:bob@example.com","fields":{}}`, http.StatusConflict},
+		{"create invalid", http.MethodPost, "/admin/resources", `{"subject":"not a subject","fields":{}}`, http.StatusUnprocessableEntity},
+		{"create bad body", http.MethodPost, "/admin/resources", `{"subject":`, http.StatusBadRequest},
+		{"update", http.MethodPut, "/admin/resources?resource=acct:user@example.com", `{"fields":{"name":"Renamed"}}`, http.StatusOK},
+		{"update missing", http.MethodPut, "/admin/resources?resource=acct:nobody@example.com", `{"fields":{}}`, http.StatusNotFound},
+		{"delete", http.MethodDelete, "/admin/resources?resource=bob@example.com", "", http.StatusNoContent},
+		{"delete missing", http.MethodDelete, "/admin/resources?resource=bob@example.com", "", http.StatusNotFound},
+		{"method not allowed", http.MethodDelete, "/admin/resources", "", http.StatusMethodNotAllowed},
+	}
+
+	// The steps build on each other, so they run in order
+	for _, step := range steps {
+		if w := do(t, h, step.method, step.target, step.body); w.Code != step.wantCode {
+			t.Fatalf("%s: expected status code %d, got %d: %s", step.name, step.wantCode, w.Code, w.Body)
+		}
+	}
+
+	fingers := s.Current().Fingers
+
+	if len(fingers) != 1 {
+		t.Fatalf("expected 1 webfinger, got %d", len(fingers))
+	}
+
+	if got := fingers["acct:user@example.com"].Properties["name"]; got != "Renamed" {
+		t.Errorf("expected the updated name, got %q", got)
+	}
+}
+
+func TestAPI_Resources_Get(t *testing.T) {
+	t.Parallel()
+
+	h, _ := newAPI(t, webfingers.Resources{
+		"user@example.com": {"name": "User"},
+	}, nil)
+
+	w := do(t, h, http.MethodGet, "/admin/resources?resource=acct:user@example.com", "")
+
+	var res admin.Resource
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("error decoding resource: %v", err)
+	}
+
+	if res.Subject != "acct:user@example.com" || res.Fields["name"] != "User" {
+		t.Errorf("unexpected resource %+v", res)
+	}
+}
+
+func TestAPI_URNs(t *testing.T) {
+	t.Parallel()
+
+	h, s := newAPI(t, webfingers.Resources{
+		"user@example.com": {"avatar": "https://example.com/user.png"},
+	}, nil)
+
+	steps := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		wantCode int
+	}{
+		{"create invalid", http.MethodPost, "/admin/urns", `{"name":"avatar","urn":"not a urn"}`, http.StatusUnprocessableEntity},
+		{"create", http.MethodPost, "/admin/urns", `{"name":"avatar","urn":"http://webfinger.net/rel/avatar"}`, http.StatusCreated},
+		{"create existing", http.MethodPost, "/admin/urns", `{"name":"avatar","urn":"http://webfinger.net/rel/avatar"}`, http.StatusConflict},
+		{"get", http.MethodGet, "/admin/urns?name=avatar", "", http.StatusOK},
+		{"update", http.MethodPut, "/admin/urns?name=avatar", `{"urn":"https://example.com/rel/avatar"}`, http.StatusOK},
+		{"update missing", http.MethodPut, "/admin/urns?name=name", `{"urn":"https://example.com/rel/name"}`, http.StatusNotFound},
+		{"list", http.MethodGet, "/admin/urns", "", http.StatusOK},
+	}
+
+	for _, step := range steps {
+		if w := do(t, h, step.method, step.target, step.body); w.Code != step.wantCode {
+			t.Fatalf("%s: expected status code %d, got %d: %s", step.name, step.wantCode, w.Code, w.Body)
+		}
+	}
+
+	// The alias is used by the live webfingers
+	links := s.Current().Fingers["acct:user@example.com"].Links
+	if len(links) != 1 || links[0].Rel != "https://example.com/rel/avatar" {
+		t.Errorf("expected the aliased rel, got %+v", links)
+	}
+
+	if w := do(t, h, http.MethodDelete, "/admin/urns?name=avatar", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	links = s.Current().Fingers["acct:user@example.com"].Links
+	if len(links) != 1 || links[0].Rel != "avatar" {
+		t.Errorf("expected the plain rel, got %+v", links)
+	}
+}
+
+func TestAPI_Reload(t *testing.T) {
+	t.Parallel()
+
+	resources := webfingers.Resources{"user@example.com": {"name": "User"}}
+
+	h, s := newAPI(t, resources, nil)
+
+	if w := do(t, h, http.MethodPost, "/admin/resources", `{"subject":"bob@example.com","fields":{}}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	// Reloading goes back to the source
+	if err := s.Reload(context.Background()); err != nil {
+		t.Fatalf("error reloading: %v", err)
+	}
+
+	if _, ok := s.Current().Fingers["acct:bob@example.com"]; ok {
+		t.Error("expected the created resource to be gone after a reload")
+	}
+}
+
+func TestAPI_LoadError(t *testing.T) {
+	t.Parallel()
+
+	api := admin.New(func(_ context.Context) (webfingers.Resources, webfingers.URNAliases, error) {
+		return webfingers.Resources{"not a subject": {}}, nil, nil
+	})
+
+	if _, err := api.Load(context.Background()); err == nil {
+		t.Error("expected an error loading invalid resources")
+	}
+
+	api = admin.New(func(_ context.Context) (webfingers.Resources, webfingers.URNAliases, error) {
+		return nil, nil, errors.New("missing file")
+	})
+
+	if _, err := api.Load(context.Background()); err == nil {
+		t.Error("expected an error loading missing files")
+	}
+}
+
+func TestAPI_Save(t *testing.T) {
+	t.Parallel()
+
+	var saved webfingers.Resources
+
+	var saveErr error
+
+	api := admin.New(func(_ context.Context) (webfingers.Resources, webfingers.URNAliases, error) {
+		return webfingers.Resources{"user@example.com": {"name": "User"}}, nil, nil
+	}, admin.WithSave(func(_ context.Context, resources webfingers.Resources, _ webfingers.URNAliases) error {
+		if saveErr != nil {
+			return saveErr
+		}
+
+		saved = resources
+
+		return nil
+	}))
+
+	fingers, _ := api.Load(context.Background())
+	s := store.New(fingers, api.Load)
+	h := api.Handler(s, []admin.Token{{Name: "ci", Value: token}})
+
+	if w := do(t, h, http.MethodDelete, "/admin/resources?resource=user@example.com", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	if saved == nil || len(saved) != 0 {
+		t.Errorf("expected the deletion to be saved, got %v", saved)
+	}
+
+	// Changes that can't be saved aren't served
+	saveErr = errors.New("read-only file system")
+
+	if w := do(t, h, http.MethodPost, "/admin/resources", `{"subject":"bob@example.com","fields":{}}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	// Files edited since they were loaded are a conflict
+	saveErr = fmt.Errorf("fingers.yml %w", fingerreader.ErrChanged)
+
+	if w := do(t, h, http.MethodPost, "/admin/resources", `{"subject":"bob@example.com","fields":{}}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	if len(s.Current().Fingers) != 0 {
+		t.Errorf("expected no webfingers, got %v", s.Current().Fingers)
+	}
+}
+
+func TestAPI_ConcurrentReload(t *testing.T) {
+	t.Parallel()
+
+	// The source is what was last saved
+	var (
+		mu    sync.Mutex
+		saved = webfingers.Resources{}
+	)
+
+	api := admin.New(func(_ context.Context) (webfingers.Resources, webfingers.URNAliases, error) {
+		mu.Lock()
+
+		resources := make(webfingers.Resources, len(saved))
+		for k, v := range saved {
+			resources[k] = v
+		}
+
+		mu.Unlock()
+
+		// Give changes time to be saved while loading
+		time.Sleep(time.Millisecond)
+
+		return resources, nil, nil
+	}, admin.WithSave(func(_ context.Context, resources webfingers.Resources, _ webfingers.URNAliases) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		saved = resources
+
+		return nil
+	}))
+
+	fingers, _ := api.Load(context.Background())
+	s := store.New(fingers, api.Load)
+	h := api.Handler(s, []admin.Token{{Name: "ci", Value: token}})
+
+	const changes = 50
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < changes; i++ {
+		wg.Add(2) //nolint:gomnd // A change and a reload
+
+		go func(i int) {
+			defer wg.Done()
+
+			body := fmt.Sprintf(`{"subject":"user%d@example.com","fields":{}}`, i)
+			if w := do(t, h, http.MethodPost, "/admin/resources", body); w.Code != http.StatusCreated {
+				t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			if err := s.Reload(context.Background()); err != nil {
+				t.Errorf("error reloading: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// The store serves the resources of the API, with none of the changes lost
+	var list []admin.Resource
+	if err := json.NewDecoder(do(t, h, http.MethodGet, "/admin/resources", "").Body).Decode(&list); err != nil {
+		t.Fatalf("error decoding resources: %v", err)
+	}
+
+	served := s.Current().Fingers
+
+	if len(list) != changes || len(served) != changes {
+		t.Fatalf("expected %d resources, got %d in the API and %d in the store", changes, len(list), len(served))
+	}
+
+	for _, res := range list {
+		if _, ok := served[res.Subject]; !ok {
+			t.Errorf("resource %s is not served", res.Subject)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"git.maronato.dev/maronato/finger/internal/middleware"
+)
+
+// ErrNoTokens is returned when the tokens file has no tokens.
+var ErrNoTokens = errors.New("no admin tokens")
+
+// Token is a bearer token allowed to use the admin API.
+type Token struct {
+	// Name identifies who uses the token, like in audit logs.
+	Name  string
+	Value string
+}
+
+// ReadTokens reads the tokens file at path. Each line has a token, or a
+// name and a token separated by a colon. Blank lines and lines starting
+// with # are ignored.
+func ReadTokens(path string) ([]Token, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening tokens file: %w", err)
+	}
+	defer f.Close()
+
+	var tokens []Token
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// Unnamed tokens are named after their line
+		token := Token{Name: "token-" + strconv.Itoa(line), Value: text}
+		if name, value, ok := strings.Cut(text, ":"); ok {
+			token = Token{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)}
+		}
+
+		if token.Name == "" || token.Value == "" {
+			return nil, fmt.Errorf("error parsing tokens file: line %d: empty name or token", line) //nolint:goerr113 // We want to return an error
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading tokens file: %w", err)
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoTokens, path)
+	}
+
+	return tokens, nil
+}
+
+type actorCtxKey struct{}
+
+// WithActor returns a copy of ctx with the name of who made the request.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// ActorFromContext returns the name of who made the request, if known.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorCtxKey{}).(string)
+
+	return actor, ok
+}
+
+// Authenticate only lets requests with one of the tokens in the
+// Authorization header through, and adds the name of the token to the
+// context and the request log.
+func Authenticate(tokens []Token) func(http.Handler) http.Handler {
+	// Compare hashes so every comparison takes the same time
+	hashes := make([][sha256.Size]byte, len(tokens))
+	for i, t := range tokens {
+		hashes[i] = sha256.Sum256([]byte(t.Value))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			got := sha256.Sum256([]byte(value))
+
+			match := -1
+
+			for i := range hashes {
+				if subtle.ConstantTimeCompare(got[:], hashes[i][:]) == 1 {
+					match = i
+				}
+			}
+
+			if !ok || match < 0 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="finger"`)
+				writeError(w, http.StatusUnauthorized, "Unauthorized")
+
+				return
+			}
+
+			actor := tokens[match].Name
+			middleware.AddLogAttrs(r.Context(), slog.String("actor", actor))
+
+			next.ServeHTTP(w, r.WithContext(WithActor(r.Context(), actor)))
+		})
+	}
+}
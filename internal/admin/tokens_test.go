@@ -0,0 +1,116 @@
+package admin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/admin"
+)
+
+func TestReadTokens(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		want    []admin.Token
+		wantErr bool
+	}{
+		{
+			name: "named and unnamed",
+			file: "# Deploy tokens\nci: abc\n\ndef\n",
+			want: []admin.Token{
+				{Name: "ci", Value: "abc"},
+				{Name: "token-4", Value: "def"},
+			},
+		},
+		{
+			name:    "empty",
+			file:    "# No tokens\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty token",
+			file:    "ci:\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "tokens")
+			if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+				t.Fatalf("error writing tokens file: %v", err)
+			}
+
+			got, err := admin.ReadTokens(path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ReadTokens() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ReadTokens() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := admin.ReadTokens(filepath.Join(t.TempDir(), "missing")); err == nil {
+			t.Error("ReadTokens() expected error, got nil")
+		}
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	tokens := []admin.Token{{Name: "ci", Value: "secret"}}
+
+	var actor string
+
+	h := admin.Authenticate(tokens)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor, _ = admin.ActorFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+	}{
+		{"no header", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "Basic secret", http.StatusUnauthorized},
+		{"valid", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/admin/resources", http.NoBody)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.wantCode {
+			t.Errorf("%s: expected status code %d, got %d", tc.name, tc.wantCode, w.Code)
+		}
+
+		if tc.wantCode == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected a WWW-Authenticate header", tc.name)
+		}
+	}
+
+	if actor != "ci" {
+		t.Errorf("expected actor ci, got %q", actor)
+	}
+}
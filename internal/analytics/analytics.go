@@ -0,0 +1,204 @@
+package analytics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"git.maronato.dev/maronato/finger/internal/privacy"
+)
+
+const (
+	// countersPerItem is how many counters are kept for each reported
+	// item, which makes the counts of the top items more accurate.
+	countersPerItem = 10
+	// maxKeyLength is the maximum length of counted resources. Longer
+	// ones are truncated so clients can't use up memory.
+	maxKeyLength = 256
+)
+
+// Option configures Analytics.
+type Option func(*Analytics)
+
+// WithClock makes the analytics read the time from now instead of
+// time.Now. It's useful for tests.
+func WithClock(now func() time.Time) Option {
+	return func(a *Analytics) {
+		a.now = now
+	}
+}
+
+// WithRedactor hides the counted resources with the redactor. Resources it
+// drops only count towards the totals.
+func WithRedactor(r *privacy.Redactor) Option {
+	return func(a *Analytics) {
+		a.redactor = r
+	}
+}
+
+// WithClosedWindows keeps the windows that end until Flush writes them.
+func WithClosedWindows() Option {
+	return func(a *Analytics) {
+		a.keepClosed = true
+	}
+}
+
+// Snapshot are the top hits and misses of a window.
+type Snapshot struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	TotalHits   uint64    `json:"totalHits"`
+	TotalMisses uint64    `json:"totalMisses"`
+	Hits        []Entry   `json:"hits"`
+	Misses      []Entry   `json:"misses"`
+}
+
+// Report is the snapshot of the current window and of the previous one.
+type Report struct {
+	Current  *Snapshot `json:"current"`
+	Previous *Snapshot `json:"previous,omitempty"`
+}
+
+// Analytics counts the most looked up resources, and the most looked up
+// missing ones, over fixed time windows.
+type Analytics struct {
+	top      int
+	window   time.Duration
+	now      func() time.Time
+	redactor *privacy.Redactor
+
+	mu          sync.Mutex
+	start       time.Time
+	totalHits   uint64
+	totalMisses uint64
+	hits        *TopK
+	misses      *TopK
+	previous    *Snapshot
+
+	// closed are the windows that ended since the last flush, kept only
+	// when flushing.
+	keepClosed bool
+	closed     []*Snapshot
+}
+
+// New creates analytics that report the top resources of every window.
+func New(top int, window time.Duration, opts ...Option) *Analytics {
+	a := &Analytics{
+		top:    top,
+		window: window,
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	a.reset(a.now())
+
+	return a
+}
+
+func (a *Analytics) reset(start time.Time) {
+	a.start = start
+	a.totalHits = 0
+	a.totalMisses = 0
+	a.hits = NewTopK(a.top * countersPerItem)
+	a.misses = NewTopK(a.top * countersPerItem)
+}
+
+// rotate starts a new window if the current one is over.
+func (a *Analytics) rotate(now time.Time) {
+	if now.Sub(a.start) < a.window {
+		return
+	}
+
+	end := a.start.Add(a.window)
+	a.previous = a.snapshot(end)
+
+	if a.keepClosed {
+		a.closed = append(a.closed, a.previous)
+	}
+
+	// Skip the windows without any lookups
+	a.reset(end.Add(now.Sub(end).Truncate(a.window)))
+}
+
+func (a *Analytics) snapshot(end time.Time) *Snapshot {
+	return &Snapshot{
+		Start:       a.start,
+		End:         end,
+		TotalHits:   a.totalHits,
+		TotalMisses: a.totalMisses,
+		Hits:        a.hits.Top(a.top),
+		Misses:      a.misses.Top(a.top),
+	}
+}
+
+// Observe counts a lookup of resource.
+func (a *Analytics) Observe(resource string, hit bool) {
+	counted := true
+	if a.redactor != nil {
+		resource, counted = a.redactor.Redact(resource)
+	}
+
+	if len(resource) > maxKeyLength {
+		// Cut on a rune boundary so the key stays valid UTF-8
+		end := maxKeyLength
+		for end > 0 && !utf8.RuneStart(resource[end]) {
+			end--
+		}
+
+		resource = resource[:end]
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rotate(a.now())
+
+	if hit {
+		a.totalHits++
+	} else {
+		a.totalMisses++
+	}
+
+	switch {
+	case !counted:
+	case hit:
+		a.hits.Add(resource)
+	default:
+		a.misses.Add(resource)
+	}
+}
+
+// Report returns the top resources of the current and previous windows.
+func (a *Analytics) Report() *Report {
+	now := a.now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rotate(now)
+
+	return &Report{
+		Current:  a.snapshot(now),
+		Previous: a.previous,
+	}
+}
+
+// Handler serves the report as JSON.
+func (a *Analytics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(a.Report())
+	})
+}
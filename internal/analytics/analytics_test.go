@@ -0,0 +1,294 @@
+package analytics_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"git.maronato.dev/maronato/finger/internal/analytics"
+	"git.maronato.dev/maronato/finger/internal/privacy"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestAnalytics(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	start := clock.Now()
+	a := analytics.New(2, time.Hour, analytics.WithClock(clock.Now))
+
+	a.Observe("acct:a@example.com", true)
+	a.Observe("acct:a@example.com", true)
+	a.Observe("acct:b@example.com", true)
+	a.Observe("acct:c@example.com", true)
+	a.Observe("acct:admin@example.com", false)
+
+	report := a.Report()
+
+	if report.Previous != nil {
+		t.Errorf("expected no previous window, got %v", report.Previous)
+	}
+
+	cur := report.Current
+	if cur.TotalHits != 4 || cur.TotalMisses != 1 {
+		t.Errorf("expected 4 hits and 1 miss, got %d and %d", cur.TotalHits, cur.TotalMisses)
+	}
+
+	// Only the top 2 are reported
+	if len(cur.Hits) != 2 || cur.Hits[0].Key != "acct:a@example.com" || cur.Hits[0].Count != 2 {
+		t.Errorf("expected acct:a@example.com on top, got %v", cur.Hits)
+	}
+
+	if len(cur.Misses) != 1 || cur.Misses[0].Key != "acct:admin@example.com" {
+		t.Errorf("expected acct:admin@example.com in the misses, got %v", cur.Misses)
+	}
+
+	// A new window starts after the window duration
+	clock.Advance(90 * time.Minute)
+	a.Observe("acct:b@example.com", true)
+
+	report = a.Report()
+
+	if report.Previous == nil || report.Previous.TotalHits != 4 {
+		t.Fatalf("expected the previous window to have 4 hits, got %v", report.Previous)
+	}
+
+	if !report.Previous.Start.Equal(start) || !report.Previous.End.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the previous window to be the first hour, got %v to %v", report.Previous.Start, report.Previous.End)
+	}
+
+	if report.Current.TotalHits != 1 || !report.Current.Start.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the current window to start after an hour with 1 hit, got %v", report.Current)
+	}
+}
+
+func TestAnalytics_LongResources(t *testing.T) {
+	t.Parallel()
+
+	a := analytics.New(1, time.Hour)
+	a.Observe(strings.Repeat("a", 10000), false)
+
+	if got := len(a.Report().Current.Misses[0].Key); got > 256 {
+		t.Errorf("expected the resource to be truncated, got %d bytes", got)
+	}
+}
+
+func TestAnalytics_LongResourcesRunes(t *testing.T) {
+	t.Parallel()
+
+	// The 256th byte falls in the middle of a rune
+	a := analytics.New(1, time.Hour)
+	a.Observe("a"+strings.Repeat("é", 200), false)
+
+	key := a.Report().Current.Misses[0].Key
+	if len(key) > 256 || !utf8.ValidString(key) {
+		t.Errorf("expected the resource to be truncated to valid UTF-8, got %d bytes: %q", len(key), key)
+	}
+
+	if want := "a" + strings.Repeat("é", 127); key != want {
+		t.Errorf("expected key %q, got %q", want, key)
+	}
+}
+
+func TestAnalytics_Handler(t *testing.T) {
+	t.Parallel()
+
+	a := analytics.New(10, time.Hour)
+	a.Observe("acct:a@example.com", true)
+
+	w := httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/analytics", http.NoBody))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var report analytics.Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("error decoding report: %v", err)
+	}
+
+	if report.Current == nil || report.Current.TotalHits != 1 {
+		t.Errorf("expected 1 hit, got %v", report.Current)
+	}
+
+	w = httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/analytics", http.NoBody))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status code %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+func TestAnalytics_Flush(t *testing.T) {
+	t.Parallel()
+
+	// newAnalytics has a lookup in the first hour, and one in the second,
+	// which is still going on
+	newAnalytics := func() *analytics.Analytics {
+		clock := newFakeClock()
+		a := analytics.New(10, time.Hour, analytics.WithClock(clock.Now), analytics.WithClosedWindows())
+
+		a.Observe("acct:a@example.com", true)
+		clock.Advance(time.Hour)
+		a.Observe("acct:admin@example.com", false)
+		clock.Advance(time.Minute)
+
+		return a
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		t.Parallel()
+
+		a := newAnalytics()
+		path := filepath.Join(t.TempDir(), "analytics.csv")
+
+		// Flushing twice only writes the closed window once
+		for i := 0; i < 2; i++ {
+			if err := a.Flush(path, false); err != nil {
+				t.Fatalf("error flushing: %v", err)
+			}
+		}
+
+		b, _ := os.ReadFile(path)
+
+		want := "start,end,outcome,resource,count,error\n" +
+			"2023-01-01T00:00:00Z,2023-01-01T01:00:00Z,hit,acct:a@example.com,1,0\n"
+
+		if string(b) != want {
+			t.Errorf("expected:\n%s\ngot:\n%s", want, b)
+		}
+
+		// The final flush adds the current window
+		if err := a.Flush(path, true); err != nil {
+			t.Fatalf("error flushing: %v", err)
+		}
+
+		b, _ = os.ReadFile(path)
+
+		want += "2023-01-01T01:00:00Z,2023-01-01T01:01:00Z,miss,acct:admin@example.com,1,0\n"
+
+		if string(b) != want {
+			t.Errorf("expected:\n%s\ngot:\n%s", want, b)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		a := newAnalytics()
+		path := filepath.Join(t.TempDir(), "analytics.jsonl")
+
+		for _, final := range []bool{false, false, true, true} {
+			if err := a.Flush(path, final); err != nil {
+				t.Fatalf("error flushing: %v", err)
+			}
+		}
+
+		b, _ := os.ReadFile(path)
+
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 lines, got %d", len(lines))
+		}
+
+		// Summing the records counts every lookup once
+		var hits, misses uint64
+
+		for _, line := range lines {
+			var snap analytics.Snapshot
+			if err := json.Unmarshal([]byte(line), &snap); err != nil {
+				t.Fatalf("error decoding snapshot: %v", err)
+			}
+
+			hits += snap.TotalHits
+			misses += snap.TotalMisses
+		}
+
+		if hits != 1 || misses != 1 {
+			t.Errorf("expected 1 hit and 1 miss in total, got %d and %d", hits, misses)
+		}
+	})
+}
+
+func TestAnalytics_Redactor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		mode privacy.Mode
+		want []string
+	}{
+		{
+			name: "plain",
+			mode: privacy.ModePlain,
+			want: []string{"acct:a@example.com"},
+		},
+		{
+			name: "hash",
+			mode: privacy.ModeHash,
+			want: []string{"28cbc9aa6ddaba3e9a9f28326fa301a1"},
+		},
+		{
+			name: "none",
+			mode: privacy.ModeNone,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := analytics.New(10, time.Hour, analytics.WithRedactor(privacy.NewRedactor(tc.mode, "key")))
+			a.Observe("acct:a@example.com", true)
+
+			report := a.Report()
+
+			// Lookups always count towards the totals
+			if report.Current.TotalHits != 1 {
+				t.Errorf("expected 1 hit, got %d", report.Current.TotalHits)
+			}
+
+			got := []string{}
+			for _, e := range report.Current.Hits {
+				got = append(got, e.Key)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("expected hits %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package analytics
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/log"
+)
+
+// flushFileMode is the file mode of new flush files.
+const flushFileMode = 0o640
+
+// csvHeader are the columns of CSV flush files.
+var csvHeader = []string{"start", "end", "outcome", "resource", "count", "error"} //nolint:gochecknoglobals // Read-only
+
+// WriteJSON writes the snapshot as a single line of JSON.
+func WriteJSON(w io.Writer, snap *Snapshot) error {
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("error encoding snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// WriteCSV writes a row for each entry of the snapshot, and the header
+// first if header is true.
+func WriteCSV(w io.Writer, snap *Snapshot, header bool) error {
+	cw := csv.NewWriter(w)
+
+	if header {
+		_ = cw.Write(csvHeader)
+	}
+
+	start := snap.Start.UTC().Format(time.RFC3339)
+	end := snap.End.UTC().Format(time.RFC3339)
+
+	for _, list := range []struct {
+		outcome string
+		entries []Entry
+	}{
+		{"hit", snap.Hits},
+		{"miss", snap.Misses},
+	} {
+		for _, e := range list.entries {
+			_ = cw.Write([]string{
+				start,
+				end,
+				list.outcome,
+				e.Key,
+				strconv.FormatUint(e.Count, 10),
+				strconv.FormatUint(e.Error, 10),
+			})
+		}
+	}
+
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("error writing snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Flush appends the windows that ended since the last flush to the file at
+// path, as CSV if it has a .csv extension or as JSON lines otherwise. If
+// final is true, the current window is also appended and a new one is
+// started, so every lookup is written once. The analytics must keep their
+// closed windows.
+func (a *Analytics) Flush(path string, final bool) error {
+	snaps := a.takeClosed(final)
+	if len(snaps) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, flushFileMode)
+	if err != nil {
+		return fmt.Errorf("error opening analytics file: %w", err)
+	}
+	defer f.Close()
+
+	asCSV := strings.EqualFold(filepath.Ext(path), ".csv")
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading analytics file: %w", err)
+	}
+
+	for i, snap := range snaps {
+		if asCSV {
+			err = WriteCSV(f, snap, i == 0 && info.Size() == 0)
+		} else {
+			err = WriteJSON(f, snap)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// takeClosed returns the windows that ended since it was last called, and
+// the current one if final is true.
+func (a *Analytics) takeClosed(final bool) []*Snapshot {
+	now := a.now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rotate(now)
+
+	snaps := a.closed
+	a.closed = nil
+
+	if final && a.totalHits+a.totalMisses > 0 {
+		snaps = append(snaps, a.snapshot(now))
+		a.reset(now)
+	}
+
+	return snaps
+}
+
+// RunFlush appends the windows that ended to the file at path every
+// interval, and the current one when the context is done.
+func (a *Analytics) RunFlush(ctx context.Context, path string, interval time.Duration) {
+	l := log.FromContext(ctx)
+
+	flush := func(final bool) {
+		if err := a.Flush(path, final); err != nil {
+			l.Error("Failed to flush analytics", slog.Any("error", err))
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush(true)
+
+			return
+		case <-ticker.C:
+			flush(false)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package analytics
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Entry is an item counted by TopK.
+type Entry struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+	// Error is how much the count may be overestimated by.
+	Error uint64 `json:"error"`
+}
+
+// TopK finds the most frequent items of a stream in bounded memory, with
+// the Space-Saving algorithm. It keeps up to capacity counters, and when
+// they are all in use, a new item replaces the least frequent one.
+type TopK struct {
+	capacity int
+	index    map[string]*counter
+	heap     counterHeap
+}
+
+type counter struct {
+	Entry
+
+	pos int
+}
+
+// NewTopK creates a TopK that keeps up to capacity counters.
+func NewTopK(capacity int) *TopK {
+	return &TopK{
+		capacity: capacity,
+		index:    make(map[string]*counter, capacity),
+		heap:     make(counterHeap, 0, capacity),
+	}
+}
+
+// Add counts an occurrence of key.
+func (t *TopK) Add(key string) {
+	if c, ok := t.index[key]; ok {
+		c.Count++
+		heap.Fix(&t.heap, c.pos)
+
+		return
+	}
+
+	if len(t.heap) < t.capacity {
+		c := &counter{Entry: Entry{Key: key, Count: 1}}
+		t.index[key] = c
+		heap.Push(&t.heap, c)
+
+		return
+	}
+
+	// Replace the least frequent item, which the new one may have
+	// occurred as many times as
+	c := t.heap[0]
+	delete(t.index, c.Key)
+
+	c.Key = key
+	c.Error = c.Count
+	c.Count++
+	t.index[key] = c
+
+	heap.Fix(&t.heap, 0)
+}
+
+// Top returns up to n of the most frequent items, most frequent first.
+func (t *TopK) Top(n int) []Entry {
+	entries := make([]Entry, 0, len(t.heap))
+
+	for _, c := range t.heap {
+		entries = append(entries, c.Entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+
+		return entries[i].Key < entries[j].Key
+	})
+
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+
+	return entries
+}
+
+// Len returns the number of items being counted.
+func (t *TopK) Len() int {
+	return len(t.heap)
+}
+
+// counterHeap is a min-heap of counters by count.
+type counterHeap []*counter
+
+func (h counterHeap) Len() int {
+	return len(h)
+}
+
+func (h counterHeap) Less(i, j int) bool {
+	return h[i].Count < h[j].Count
+}
+
+func (h counterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *counterHeap) Push(x any) {
+	c, _ := x.(*counter)
+	c.pos = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *counterHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return c
+}
@@ -0,0 +1,65 @@
+package analytics_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/analytics"
+)
+
+func TestTopK(t *testing.T) {
+	t.Parallel()
+
+	t.Run("counts exactly below capacity", func(t *testing.T) {
+		t.Parallel()
+
+		topk := analytics.NewTopK(10)
+
+		for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+			topk.Add(key)
+		}
+
+		want := []analytics.Entry{
+			{Key: "a", Count: 3},
+			{Key: "b", Count: 2},
+		}
+
+		if got := topk.Top(2); !reflect.DeepEqual(got, want) {
+			t.Errorf("Top() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("finds heavy hitters in bounded memory", func(t *testing.T) {
+		t.Parallel()
+
+		topk := analytics.NewTopK(20)
+
+		// A scanner sends lots of distinct keys between the popular ones
+		for i := 0; i < 10000; i++ {
+			topk.Add("scan-" + strconv.Itoa(i))
+
+			if i%5 == 0 {
+				topk.Add("popular")
+			}
+
+			if i%10 == 0 {
+				topk.Add("common")
+			}
+		}
+
+		if topk.Len() != 20 {
+			t.Fatalf("expected 20 counters, got %d", topk.Len())
+		}
+
+		top := topk.Top(2)
+		if top[0].Key != "popular" || top[1].Key != "common" {
+			t.Fatalf("expected popular and common on top, got %v", top)
+		}
+
+		// Counts are never underestimated
+		if top[0].Count < 2000 || top[0].Count-top[0].Error > 2000 {
+			t.Errorf("expected count of about 2000, got %d with error %d", top[0].Count, top[0].Error)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/tracing"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+// fileMode is the file mode of new audit files.
+const fileMode = 0o640
+
+// Actions of the events that aren't made through the admin API.
+const (
+	ActionReload = "reload"
+	ActionUpdate = "update"
+)
+
+// Types of the fields of a webfinger.
+const (
+	FieldProperty = "property"
+	FieldLink     = "link"
+)
+
+// FieldChange is a property or link that was added, removed or changed.
+// Old is empty for added fields and New for removed ones.
+type FieldChange struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// Change lists the fields of a webfinger that changed.
+type Change struct {
+	Subject string        `json:"subject"`
+	Fields  []FieldChange `json:"fields,omitempty"`
+}
+
+// Event is a change to the webfingers being served.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Added     []Change  `json:"added,omitempty"`
+	Removed   []Change  `json:"removed,omitempty"`
+	Changed   []Change  `json:"changed,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type actionKey struct{}
+
+// WithAction returns a copy of ctx with the action that changes the
+// webfingers.
+func WithAction(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, actionKey{}, action)
+}
+
+// ActionFromContext returns the action stored in ctx, or ActionUpdate.
+func ActionFromContext(ctx context.Context) string {
+	if action, ok := ctx.Value(actionKey{}).(string); ok {
+		return action
+	}
+
+	return ActionUpdate
+}
+
+// NewEvent creates an event with the differences between prev and next,
+// and the action and request ID in ctx.
+func NewEvent(ctx context.Context, prev, next webfingers.WebFingers) Event {
+	e := Event{
+		Time:   time.Now().UTC(),
+		Action: ActionFromContext(ctx),
+	}
+
+	e.RequestID, _ = tracing.RequestIDFromContext(ctx)
+	e.Added, e.Removed, e.Changed = Diff(prev, next)
+
+	return e
+}
+
+// Empty reports whether the event has no changes and no error.
+func (e *Event) Empty() bool {
+	return len(e.Added) == 0 && len(e.Removed) == 0 && len(e.Changed) == 0 && e.Error == ""
+}
+
+// Diff returns the webfingers that were added, removed and changed from
+// prev to next, sorted by subject.
+func Diff(prev, next webfingers.WebFingers) (added, removed, changed []Change) {
+	for _, subject := range sortedKeys(prev, next) {
+		p, n := prev[subject], next[subject]
+
+		c := Change{Subject: subject, Fields: diffFields(p, n)}
+
+		switch {
+		case p == nil:
+			added = append(added, c)
+		case n == nil:
+			removed = append(removed, c)
+		case len(c.Fields) > 0:
+			changed = append(changed, c)
+		}
+	}
+
+	return added, removed, changed
+}
+
+// diffFields returns the properties and then the links that differ
+// between the webfingers, either of which may be nil.
+func diffFields(prev, next *webfingers.WebFinger) []FieldChange {
+	changes := diffMaps(FieldProperty, properties(prev), properties(next))
+
+	return append(changes, diffMaps(FieldLink, links(prev), links(next))...)
+}
+
+func diffMaps(typ string, prev, next map[string]string) []FieldChange {
+	var changes []FieldChange
+
+	for _, name := range sortedKeys(prev, next) {
+		if old, cur := prev[name], next[name]; old != cur {
+			changes = append(changes, FieldChange{Type: typ, Name: name, Old: old, New: cur})
+		}
+	}
+
+	return changes
+}
+
+func properties(f *webfingers.WebFinger) map[string]string {
+	if f == nil {
+		return nil
+	}
+
+	return f.Properties
+}
+
+func links(f *webfingers.WebFinger) map[string]string {
+	if f == nil {
+		return nil
+	}
+
+	m := make(map[string]string, len(f.Links))
+	for _, l := range f.Links {
+		m[l.Rel] = l.Href
+	}
+
+	return m
+}
+
+// sortedKeys returns the keys of both maps, sorted.
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+
+	for k := range a {
+		keys = append(keys, k)
+	}
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Log appends events to a JSON lines file.
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+// New creates a log that appends to the file at path.
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// Write appends the event to the file, creating it if needed.
+func (l *Log) Write(e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error encoding audit event: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
+	if err != nil {
+		return fmt.Errorf("error opening audit file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("error writing audit event: %w", err)
+	}
+
+	// Events must survive a crash
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("error syncing audit file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/audit"
+	"git.maronato.dev/maronato/finger/internal/tracing"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	prev := webfingers.WebFingers{
+		"acct:alice@example.com": {
+			Subject:    "acct:alice@example.com",
+			Properties: map[string]string{"name": "Alice", "age": "30"},
+			Links:      []webfingers.Link{{Rel: "avatar", Href: "https://example.com/alice.png"}},
+		},
+		"acct:bob@example.com": {
+			Subject:    "acct:bob@example.com",
+			Properties: map[string]string{"name": "Bob"},
+		},
+		"acct:carol@example.com": {
+			Subject:    "acct:carol@example.com",
+			Properties: map[string]string{"name": "Carol"},
+		},
+	}
+
+	next := webfingers.WebFingers{
+		"acct:alice@example.com": {
+			Subject:    "acct:alice@example.com",
+			Properties: map[string]string{"name": "Alice Liddell", "age": "30"},
+			Links:      []webfingers.Link{{Rel: "profile", Href: "https://alice.example.com"}},
+		},
+		"acct:carol@example.com": {
+			Subject:    "acct:carol@example.com",
+			Properties: map[string]string{"name": "Carol"},
+		},
+		"acct:dave@example.com": {
+			Subject: "acct:dave@example.com",
+			Links:   []webfingers.Link{{Rel: "avatar", Href: "https://example.com/dave.png"}},
+		},
+	}
+
+	added, removed, changed := audit.Diff(prev, next)
+
+	wantAdded := []audit.Change{{
+		Subject: "acct:dave@example.com",
+		Fields: []audit.FieldChange{
+			{Type: audit.FieldLink, Name: "avatar", New: "https://example.com/dave.png"},
+		},
+	}}
+	wantRemoved := []audit.Change{{
+		Subject: "acct:bob@example.com",
+		Fields: []audit.FieldChange{
+			{Type: audit.FieldProperty, Name: "name", Old: "Bob"},
+		},
+	}}
+	wantChanged := []audit.Change{{
+		Subject: "acct:alice@example.com",
+		Fields: []audit.FieldChange{
+			{Type: audit.FieldProperty, Name: "name", Old: "Alice", New: "Alice Liddell"},
+			{Type: audit.FieldLink, Name: "avatar", Old: "https://example.com/alice.png"},
+			{Type: audit.FieldLink, Name: "profile", New: "https://alice.example.com"},
+		},
+	}}
+
+	if !reflect.DeepEqual(added, wantAdded) {
+		t.Errorf("Diff() added = %+v, want %+v", added, wantAdded)
+	}
+
+	if !reflect.DeepEqual(removed, wantRemoved) {
+		t.Errorf("Diff() removed = %+v, want %+v", removed, wantRemoved)
+	}
+
+	if !reflect.DeepEqual(changed, wantChanged) {
+		t.Errorf("Diff() changed = %+v, want %+v", changed, wantChanged)
+	}
+
+	// Nothing changes between the same webfingers
+	if added, removed, changed := audit.Diff(next, next); added != nil || removed != nil || changed != nil {
+		t.Errorf("Diff() of the same webfingers = %v, %v, %v", added, removed, changed)
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	t.Parallel()
+
+	ctx := tracing.WithRequestID(context.Background(), "req-1")
+
+	e := audit.NewEvent(ctx, nil, webfingers.WebFingers{})
+	if e.Action != audit.ActionUpdate || e.RequestID != "req-1" || e.Time.IsZero() {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	e = audit.NewEvent(audit.WithAction(ctx, audit.ActionReload), nil, nil)
+	if e.Action != audit.ActionReload {
+		t.Errorf("expected action %s, got %s", audit.ActionReload, e.Action)
+	}
+
+	// Events without changes are empty
+	if !e.Empty() {
+		t.Errorf("expected event %+v to be empty", e)
+	}
+
+	e = audit.NewEvent(ctx, nil, webfingers.WebFingers{"acct:a@example.com": {Subject: "acct:a@example.com"}})
+	if e.Empty() {
+		t.Errorf("expected event %+v not to be empty", e)
+	}
+}
+
+func TestLog_Write(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := audit.New(path)
+
+	events := []audit.Event{
+		{Action: audit.ActionReload, Added: []audit.Change{{Subject: "acct:user@example.com"}}},
+		{Action: "delete_resource", Actor: "ci", Removed: []audit.Change{{Subject: "acct:user@example.com"}}},
+	}
+
+	for _, e := range events {
+		if err := l.Write(e); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("error opening audit file: %v", err)
+	}
+	defer f.Close()
+
+	// Events are appended one per line
+	var got []audit.Event
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e audit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("error decoding line %q: %v", scanner.Text(), err)
+		}
+
+		got = append(got, e)
+	}
+
+	if !reflect.DeepEqual(got, events) {
+		t.Errorf("got events %+v, want %+v", got, events)
+	}
+}
@@ -0,0 +1,169 @@
+package ban
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CleanupInterval is how often clients that are no longer tracked are
+// removed.
+const CleanupInterval = time.Minute
+
+// Option configures a Tracker.
+type Option func(*Tracker)
+
+// WithClock makes the tracker read the time from now instead of
+// time.Now. It's useful for tests.
+func WithClock(now func() time.Time) Option {
+	return func(t *Tracker) {
+		t.now = now
+	}
+}
+
+// WithOnBan calls fn every time a client is banned.
+func WithOnBan(fn func(key string, until time.Time)) Option {
+	return func(t *Tracker) {
+		t.onBan = fn
+	}
+}
+
+// client holds the recent misses of a single client.
+type client struct {
+	// misses are the times of the most recent misses, oldest first.
+	// At most threshold of them are kept.
+	misses      []time.Time
+	bannedUntil time.Time
+}
+
+// Tracker counts the misses of each client over a sliding window and bans
+// the clients that go over a threshold.
+type Tracker struct {
+	threshold int
+	window    time.Duration
+	duration  time.Duration
+	now       func() time.Time
+	onBan     func(key string, until time.Time)
+
+	mu      sync.Mutex
+	clients map[string]*client
+}
+
+// New creates a tracker that bans clients with threshold misses within
+// window for the given duration.
+func New(threshold int, window, duration time.Duration, opts ...Option) *Tracker {
+	t := &Tracker{
+		threshold: threshold,
+		window:    window,
+		duration:  duration,
+		now:       time.Now,
+		clients:   make(map[string]*client),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Miss records a miss of key and reports whether it got the client banned.
+func (t *Tracker) Miss(key string) bool {
+	now := t.now()
+
+	t.mu.Lock()
+
+	c, ok := t.clients[key]
+	if !ok {
+		c = &client{misses: make([]time.Time, 0, t.threshold)}
+		t.clients[key] = c
+	}
+
+	// Banned clients are not counted again
+	if now.Before(c.bannedUntil) {
+		t.mu.Unlock()
+
+		return false
+	}
+
+	// Forget the oldest miss if there's no room for a new one
+	if len(c.misses) == t.threshold {
+		c.misses = append(c.misses[:0], c.misses[1:]...)
+	}
+
+	c.misses = append(c.misses, now)
+
+	// Ban the client if every miss kept is inside the window
+	banned := len(c.misses) == t.threshold && now.Sub(c.misses[0]) < t.window
+	if banned {
+		c.bannedUntil = now.Add(t.duration)
+		c.misses = c.misses[:0]
+	}
+
+	until := c.bannedUntil
+
+	t.mu.Unlock()
+
+	if banned && t.onBan != nil {
+		t.onBan(key, until)
+	}
+
+	return banned
+}
+
+// Banned reports whether key is banned and for how much longer.
+func (t *Tracker) Banned(key string) (bool, time.Duration) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.clients[key]
+	if !ok || !now.Before(c.bannedUntil) {
+		return false, 0
+	}
+
+	return true, c.bannedUntil.Sub(now)
+}
+
+// Len returns the number of clients being tracked.
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.clients)
+}
+
+// Cleanup removes the clients that are not banned and have no misses left
+// in the window.
+func (t *Tracker) Cleanup() {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, c := range t.clients {
+		if now.Before(c.bannedUntil) {
+			continue
+		}
+
+		if len(c.misses) == 0 || now.Sub(c.misses[len(c.misses)-1]) >= t.window {
+			delete(t.clients, key)
+		}
+	}
+}
+
+// Run removes stale clients every interval until the context is done.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.Cleanup()
+		}
+	}
+}
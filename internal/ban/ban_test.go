@@ -0,0 +1,139 @@
+package ban_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/ban"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+
+	var bans []string
+
+	tr := ban.New(3, time.Minute, 10*time.Minute,
+		ban.WithClock(clock.Now),
+		ban.WithOnBan(func(key string, until time.Time) {
+			bans = append(bans, key)
+
+			if want := clock.Now().Add(10 * time.Minute); !until.Equal(want) {
+				t.Errorf("expected ban until %v, got %v", want, until)
+			}
+		}),
+	)
+
+	// Misses spread over more than the window don't ban
+	for i := 0; i < 5; i++ {
+		if tr.Miss("a") {
+			t.Fatalf("expected miss %d not to ban", i)
+		}
+
+		clock.Advance(30 * time.Second)
+	}
+
+	if banned, _ := tr.Banned("a"); banned {
+		t.Fatal("expected client not to be banned")
+	}
+
+	// Misses inside the window do
+	tr.Miss("b")
+	clock.Advance(10 * time.Second)
+	tr.Miss("b")
+	clock.Advance(10 * time.Second)
+
+	if !tr.Miss("b") {
+		t.Fatal("expected the third miss to ban")
+	}
+
+	banned, remaining := tr.Banned("b")
+	if !banned || remaining != 10*time.Minute {
+		t.Fatalf("expected client to be banned for %v, got %v %v", 10*time.Minute, banned, remaining)
+	}
+
+	// Misses while banned are ignored
+	if tr.Miss("b") {
+		t.Error("expected banned client not to be banned again")
+	}
+
+	// Other clients are not affected
+	if banned, _ := tr.Banned("c"); banned {
+		t.Error("expected another client not to be banned")
+	}
+
+	// Bans expire
+	clock.Advance(10 * time.Minute)
+
+	if banned, _ := tr.Banned("b"); banned {
+		t.Error("expected ban to expire")
+	}
+
+	// And the count starts over
+	if tr.Miss("b") {
+		t.Error("expected miss after the ban not to ban")
+	}
+
+	if len(bans) != 1 || bans[0] != "b" {
+		t.Errorf("expected a single ban of b, got %v", bans)
+	}
+}
+
+func TestTracker_Cleanup(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	tr := ban.New(2, time.Minute, 10*time.Minute, ban.WithClock(clock.Now))
+
+	tr.Miss("a")
+	tr.Miss("b")
+	tr.Miss("b")
+
+	// Clients with misses in the window are kept, and so are banned ones
+	clock.Advance(30 * time.Second)
+	tr.Cleanup()
+
+	if got := tr.Len(); got != 2 {
+		t.Fatalf("expected 2 clients, got %d", got)
+	}
+
+	clock.Advance(30 * time.Second)
+	tr.Cleanup()
+
+	if got := tr.Len(); got != 1 {
+		t.Fatalf("expected 1 client, got %d", got)
+	}
+
+	clock.Advance(10 * time.Minute)
+	tr.Cleanup()
+
+	if got := tr.Len(); got != 0 {
+		t.Fatalf("expected 0 clients, got %d", got)
+	}
+}
@@ -0,0 +1,173 @@
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPCtxKey struct{}
+
+// WithIP returns a copy of ctx carrying the client IP.
+func WithIP(ctx context.Context, ip netip.Addr) context.Context {
+	return context.WithValue(ctx, clientIPCtxKey{}, ip)
+}
+
+// FromContext returns the client IP stored in ctx, if any.
+func FromContext(ctx context.Context) (netip.Addr, bool) {
+	ip, ok := ctx.Value(clientIPCtxKey{}).(netip.Addr)
+
+	return ip, ok
+}
+
+// FromRequest returns the IP of the client that made the request. The
+// client IP set in the request context takes precedence over the address
+// of the connection.
+func FromRequest(r *http.Request) (netip.Addr, bool) {
+	if ip, ok := FromContext(r.Context()); ok {
+		return ip, true
+	}
+
+	return RemoteIP(r)
+}
+
+// RemoteIP returns the IP address of the connection. It returns false for
+// connections without one, like those over Unix sockets.
+func RemoteIP(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return parseIP(host)
+}
+
+// Headers the client IP can be read from.
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-Ip"
+)
+
+// Resolve finds the IP of the client behind the trusted proxies. Requests
+// that come from a trusted proxy, or over a Unix socket if trustSocket is
+// true, have their client IP read from the header, which must be the one
+// the proxy sets. Other headers are ignored, since clients can send them
+// through the proxy. The connection's IP is used otherwise.
+func Resolve(r *http.Request, trusted []netip.Prefix, header string, trustSocket bool) (netip.Addr, bool) {
+	peer, ok := RemoteIP(r)
+
+	switch {
+	case ok && !isTrusted(trusted, peer):
+		return peer, true
+	case !ok && !trustSocket:
+		// Any local process may be connected to the socket
+		return peer, false
+	}
+
+	values := r.Header.Values(header)
+	if len(values) == 0 {
+		return peer, ok
+	}
+
+	var chain []string
+	if http.CanonicalHeaderKey(header) == HeaderForwarded {
+		chain = parseForwarded(values)
+	} else {
+		chain = parseList(values)
+	}
+
+	if ip, ok := fromChain(chain, trusted); ok {
+		return ip, true
+	}
+
+	return peer, ok
+}
+
+// fromChain returns the client IP from a list of addresses appended by
+// each proxy. The first untrusted address from the right is the client,
+// since anything before it may have been forged.
+func fromChain(chain []string, trusted []netip.Prefix) (netip.Addr, bool) {
+	var (
+		client netip.Addr
+		found  bool
+	)
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip, ok := parseIP(chain[i])
+		if !ok {
+			// Unknown or obfuscated addresses can't be trusted past
+			break
+		}
+
+		client, found = ip, true
+
+		if !isTrusted(trusted, ip) {
+			break
+		}
+	}
+
+	return client, found
+}
+
+// parseList splits comma separated header values.
+func parseList(values []string) []string {
+	var list []string
+
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(v))
+		}
+	}
+
+	return list
+}
+
+// parseForwarded returns the "for" parameters of RFC 7239 Forwarded
+// headers, in order.
+func parseForwarded(values []string) []string {
+	var list []string
+
+	for _, element := range parseList(values) {
+		for _, pair := range strings.Split(element, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || !strings.EqualFold(key, "for") {
+				continue
+			}
+
+			list = append(list, strings.Trim(value, `"`))
+		}
+	}
+
+	return list
+}
+
+// parseIP parses an IP that may have a port or be in brackets.
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	ip, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	// Treat IPv4-mapped IPv6 addresses as IPv4
+	return ip.Unmap(), true
+}
+
+// isTrusted reports whether any of the prefixes contains ip.
+func isTrusted(trusted []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,192 @@
+package clientip_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/clientip"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		headers    map[string][]string
+		socket     bool
+		want       string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "192.0.2.1:1234",
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "untrusted peer headers are ignored",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "x-forwarded-for",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "x-forwarded-for skips trusted proxies",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.9, 198.51.100.1", "10.0.0.2"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "x-forwarded-for with only trusted proxies",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "[2001:db8::1]:1234",
+			header:     clientip.HeaderForwarded,
+			headers: map[string][]string{
+				"Forwarded": {`for=198.51.100.1;proto=https, for="[2001:db8::2]:4711"`},
+			},
+			want: "198.51.100.1",
+		},
+		{
+			name:       "forwarded with a port",
+			remoteAddr: "10.0.0.1:1234",
+			header:     clientip.HeaderForwarded,
+			headers:    map[string][]string{"Forwarded": {`For="[2001:db8:cafe::17]:4711"`}},
+			want:       "2001:db8:cafe::17",
+		},
+		{
+			name:       "spoofed forwarded is ignored",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=192.0.2.66"},
+				"X-Forwarded-For": {"198.51.100.1"},
+			},
+			want: "198.51.100.1",
+		},
+		{
+			name:       "spoofed x-forwarded-for is ignored",
+			remoteAddr: "10.0.0.1:1234",
+			header:     clientip.HeaderForwarded,
+			headers: map[string][]string{
+				"Forwarded":       {"for=198.51.100.1"},
+				"X-Forwarded-For": {"192.0.2.66"},
+			},
+			want: "198.51.100.1",
+		},
+		{
+			name:       "spoofed x-real-ip is ignored",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Real-Ip": {"192.0.2.66"}},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "obfuscated forwarded uses the peer",
+			remoteAddr: "10.0.0.1:1234",
+			header:     clientip.HeaderForwarded,
+			headers: map[string][]string{
+				"Forwarded": {"for=_hidden"},
+				"X-Real-Ip": {"198.51.100.1"},
+			},
+			want: "10.0.0.1",
+		},
+		{
+			name:       "x-real-ip",
+			remoteAddr: "10.0.0.1:1234",
+			header:     clientip.HeaderXRealIP,
+			headers:    map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "trusted peer without headers",
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "unix socket",
+			remoteAddr: "@",
+			headers:    map[string][]string{"X-Forwarded-For": {"::ffff:198.51.100.1"}},
+			socket:     true,
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "untrusted unix socket",
+			remoteAddr: "@",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:       "invalid IP",
+		},
+		{
+			name:       "unix socket without headers",
+			remoteAddr: "@",
+			socket:     true,
+			want:       "invalid IP",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.RemoteAddr = tc.remoteAddr
+
+			for key, values := range tc.headers {
+				r.Header[key] = values
+			}
+
+			header := tc.header
+			if header == "" {
+				header = clientip.HeaderXForwardedFor
+			}
+
+			ip, _ := clientip.Resolve(r, trusted, header, tc.socket)
+			if got := ip.String(); got != tc.want {
+				t.Errorf("Resolve() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	if ip, _ := clientip.FromRequest(r); ip.String() != "192.0.2.1" {
+		t.Errorf("expected the remote address, got %v", ip)
+	}
+
+	// The IP in the context takes precedence
+	ctx := clientip.WithIP(context.Background(), netip.MustParseAddr("198.51.100.1"))
+	r = r.WithContext(ctx)
+
+	if ip, _ := clientip.FromRequest(r); ip.String() != "198.51.100.1" {
+		t.Errorf("expected the context IP, got %v", ip)
+	}
+
+	// Requests without an IP are reported
+	r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.RemoteAddr = "@"
+
+	if _, ok := clientip.FromRequest(r); ok {
+		t.Error("expected no IP")
+	}
+}
@@ -4,7 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"net/netip"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/clientip"
+	"git.maronato.dev/maronato/finger/webfingers"
 )
 
 const (
@@ -16,32 +25,237 @@ const (
 	DefaultURNPath = "urns.yml"
 	// DefaultFingerPath is the default file path to the webfinger definition file.
 	DefaultFingerPath = "fingers.yml"
+	// DefaultCompressMinSize is the default minimum response size, in bytes,
+	// for responses to be compressed.
+	DefaultCompressMinSize = 1024
+	// DefaultSocketMode is the default file mode of Unix sockets.
+	DefaultSocketMode = "0660"
+	// DefaultRateLimitBurst is the default number of requests a client
+	// can make at once when rate limiting is enabled.
+	DefaultRateLimitBurst = 20
+	// DefaultClientIPHeader is the default header trusted proxies set to
+	// the client IP.
+	DefaultClientIPHeader = clientip.HeaderXForwardedFor
+	// DefaultBanWindow is the default window in which misses are counted.
+	DefaultBanWindow = time.Minute
+	// DefaultBanDuration is the default duration of bans.
+	DefaultBanDuration = 15 * time.Minute
+	// DefaultLogMaxSize is the default size, in megabytes, at which log
+	// files are rotated.
+	DefaultLogMaxSize = 100
+	// DefaultLogMaxBackups is the default number of rotated log files kept.
+	DefaultLogMaxBackups = 3
+	// DefaultAnalyticsTop is the default number of top resources reported.
+	DefaultAnalyticsTop = 10
+	// DefaultAnalyticsWindow is the default window of the analytics.
+	DefaultAnalyticsWindow = time.Hour
+	// DefaultAnalyticsFlushInterval is the default interval at which
+	// analytics are written to their file.
+	DefaultAnalyticsFlushInterval = 5 * time.Minute
+	// DefaultLogSyslogAddr is the default address of the syslog daemon.
+	DefaultLogSyslogAddr = "unix:/dev/log"
+	// DefaultReadTimeout is the default maximum duration for reading the
+	// entire request, including the body.
+	DefaultReadTimeout = 5 * time.Second
+	// DefaultWriteTimeout is the default maximum duration before timing
+	// out writes of the response.
+	DefaultWriteTimeout = 10 * time.Second
+	// DefaultIdleTimeout is the default maximum amount of time to wait for
+	// the next request when keep-alives are enabled.
+	DefaultIdleTimeout = 30 * time.Second
+	// DefaultReadHeaderTimeout is the default amount of time allowed to
+	// read request headers.
+	DefaultReadHeaderTimeout = 2 * time.Second
+	// DefaultRequestTimeout is the default maximum duration for the
+	// entire request.
+	DefaultRequestTimeout = 7 * 24 * time.Hour
+	// DefaultShutdownTimeout is the default time given to open
+	// connections to finish before they are closed on shutdown.
+	DefaultShutdownTimeout = 10 * time.Second
+
+	// LogFormatJSON writes logs as JSON objects.
+	LogFormatJSON = "json"
+	// LogFormatText writes logs as key=value pairs.
+	LogFormatText = "text"
+
+	// LogOutputStderr writes logs to the standard error.
+	LogOutputStderr = "stderr"
+	// LogOutputStdout writes logs to the standard output.
+	LogOutputStdout = "stdout"
+	// LogOutputSyslog sends logs to a syslog daemon.
+	LogOutputSyslog = "syslog"
+
+	// LogLookupsPlain logs the looked up resources and rels as they are.
+	LogLookupsPlain = "plain"
+	// LogLookupsHash logs a keyed hash of the looked up resources and rels.
+	LogLookupsHash = "hash"
+	// LogLookupsNone doesn't log the looked up resources and rels.
+	LogLookupsNone = "none"
+
+	// UnixSocketPrefix is the host prefix that makes the server listen
+	// on a Unix socket, as in "unix:/run/finger.sock".
+	UnixSocketPrefix = "unix:"
 )
 
 // ErrInvalidConfig is returned when the config is invalid.
 var ErrInvalidConfig = errors.New("invalid config")
 
 type Config struct {
+	// Version is the version of the running binary.
+	Version string
+
+	// ConfigPath is the config file the other settings may be read from.
+	ConfigPath string
+
 	Debug      bool
+	LogFormat  string
+	LogLevel   string
+	LogOutput  string
 	Host       string
 	Port       string
 	URNPath    string
 	FingerPath string
+
+	CacheMaxAge         time.Duration
+	CacheResourceMaxAge []string
+
+	Compress        bool
+	CompressMinSize int
+
+	TLSCert          string
+	TLSKey           string
+	HTTPRedirectPort string
+
+	SocketMode string
+
+	Metrics         bool
+	AdminAddr       string
+	AdminTokensFile string
+	AdminPersist    bool
+
+	AuditFile string
+
+	RateLimit          float64
+	RateLimitBurst     int
+	RateLimitAllowlist []string
+
+	TrustedProxies  []string
+	ClientIPHeader  string
+	TrustUnixSocket bool
+
+	BanThreshold int
+	BanWindow    time.Duration
+	BanDuration  time.Duration
+	BanTarpit    time.Duration
+
+	LogMaxSize    int
+	LogMaxBackups int
+	LogSyslogAddr string
+
+	LogLookups    string
+	LogLookupsKey string
+
+	ReadyMaxAge time.Duration
+
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	RequestTimeout    time.Duration
+	ShutdownTimeout   time.Duration
+
+	Analytics              bool
+	AnalyticsTop           int
+	AnalyticsWindow        time.Duration
+	AnalyticsFile          string
+	AnalyticsFlushInterval time.Duration
 }
 
 func NewConfig() *Config {
 	return &Config{
+		LogFormat:  LogFormatJSON,
+		LogLevel:   "info",
+		LogOutput:  LogOutputStderr,
 		Host:       DefaultHost,
 		Port:       DefaultPort,
 		URNPath:    DefaultURNPath,
 		FingerPath: DefaultFingerPath,
+
+		Compress:        true,
+		CompressMinSize: DefaultCompressMinSize,
+
+		SocketMode: DefaultSocketMode,
+
+		RateLimitBurst: DefaultRateLimitBurst,
+		ClientIPHeader: DefaultClientIPHeader,
+
+		BanWindow:   DefaultBanWindow,
+		BanDuration: DefaultBanDuration,
+
+		LogMaxSize:    DefaultLogMaxSize,
+		LogMaxBackups: DefaultLogMaxBackups,
+		LogSyslogAddr: DefaultLogSyslogAddr,
+
+		LogLookups: LogLookupsPlain,
+
+		ReadTimeout:       DefaultReadTimeout,
+		WriteTimeout:      DefaultWriteTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		RequestTimeout:    DefaultRequestTimeout,
+		ShutdownTimeout:   DefaultShutdownTimeout,
+
+		AnalyticsTop:           DefaultAnalyticsTop,
+		AnalyticsWindow:        DefaultAnalyticsWindow,
+		AnalyticsFlushInterval: DefaultAnalyticsFlushInterval,
 	}
 }
 
 func (c *Config) GetAddr() string {
+	// Unix sockets have no port
+	if c.IsUnixSocket() {
+		return c.Host
+	}
+
 	return net.JoinHostPort(c.Host, c.Port)
 }
 
+// IsUnixSocket reports whether the server listens on a Unix socket.
+func (c *Config) IsUnixSocket() bool {
+	return strings.HasPrefix(c.Host, UnixSocketPrefix)
+}
+
+// GetSocketPath returns the path of the Unix socket the server listens on.
+func (c *Config) GetSocketPath() string {
+	return strings.TrimPrefix(c.Host, UnixSocketPrefix)
+}
+
+// GetSocketMode parses the octal file mode of the Unix socket.
+func (c *Config) GetSocketMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil || mode > uint64(os.ModePerm) {
+		return 0, fmt.Errorf("%w: invalid socket mode %q", ErrInvalidConfig, c.SocketMode)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// GetRedirectAddr returns the address of the HTTP to HTTPS redirect listener.
+func (c *Config) GetRedirectAddr() string {
+	return net.JoinHostPort(c.Host, c.HTTPRedirectPort)
+}
+
+// AdminAPIEnabled reports whether resources can be managed through the
+// admin API, which is the case when there are tokens to use it.
+func (c *Config) AdminAPIEnabled() bool {
+	return c.AdminTokensFile != ""
+}
+
+// TLSEnabled reports whether the server should serve HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCert != "" && c.TLSKey != ""
+}
+
 func (c *Config) Validate() error {
 	if c.Host == "" {
 		return fmt.Errorf("%w: host is empty", ErrInvalidConfig)
@@ -55,6 +269,16 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 	}
 
+	if c.IsUnixSocket() {
+		if c.GetSocketPath() == "" {
+			return fmt.Errorf("%w: socket path is empty", ErrInvalidConfig)
+		}
+
+		if _, err := c.GetSocketMode(); err != nil {
+			return err
+		}
+	}
+
 	if c.URNPath == "" {
 		return fmt.Errorf("%w: urn path is empty", ErrInvalidConfig)
 	}
@@ -63,5 +287,320 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("%w: finger path is empty", ErrInvalidConfig)
 	}
 
+	if c.CacheMaxAge < 0 {
+		return fmt.Errorf("%w: cache max age is negative", ErrInvalidConfig)
+	}
+
+	if _, err := c.GetResourceMaxAges(); err != nil {
+		return err
+	}
+
+	if c.CompressMinSize < 0 {
+		return fmt.Errorf("%w: compress min size is negative", ErrInvalidConfig)
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("%w: both the TLS certificate and key must be set", ErrInvalidConfig)
+	}
+
+	if c.HTTPRedirectPort != "" && !c.TLSEnabled() {
+		return fmt.Errorf("%w: the HTTP redirect port requires TLS", ErrInvalidConfig)
+	}
+
+	if c.HTTPRedirectPort != "" && c.IsUnixSocket() {
+		return fmt.Errorf("%w: the HTTP redirect port can't be used with a Unix socket", ErrInvalidConfig)
+	}
+
+	if c.AdminAddr != "" {
+		if _, _, err := net.SplitHostPort(c.AdminAddr); err != nil {
+			return fmt.Errorf("%w: invalid admin address: %w", ErrInvalidConfig, err)
+		}
+	}
+
+	if c.AdminAPIEnabled() && c.AdminAddr == "" {
+		return fmt.Errorf("%w: the admin API requires an admin address", ErrInvalidConfig)
+	}
+
+	if c.AdminPersist && !c.AdminAPIEnabled() {
+		return fmt.Errorf("%w: persisting admin changes requires the admin API", ErrInvalidConfig)
+	}
+
+	if c.AuditFile != "" && (c.AuditFile == c.LogOutput || c.AuditFile == c.AnalyticsFile) {
+		return fmt.Errorf("%w: the audit file must be separate from the log and analytics files", ErrInvalidConfig)
+	}
+
+	if c.RateLimit < 0 {
+		return fmt.Errorf("%w: rate limit is negative", ErrInvalidConfig)
+	}
+
+	if c.RateLimit > 0 && c.RateLimitBurst < 1 {
+		return fmt.Errorf("%w: rate limit burst must be at least 1", ErrInvalidConfig)
+	}
+
+	if _, err := c.GetRateLimitAllowlist(); err != nil {
+		return err
+	}
+
+	if _, err := c.GetTrustedProxies(); err != nil {
+		return err
+	}
+
+	if _, err := c.GetClientIPHeader(); err != nil {
+		return err
+	}
+
+	if c.TrustUnixSocket && !c.IsUnixSocket() {
+		return fmt.Errorf("%w: trusting the Unix socket requires listening on one", ErrInvalidConfig)
+	}
+
+	if c.BanThreshold < 0 {
+		return fmt.Errorf("%w: ban threshold is negative", ErrInvalidConfig)
+	}
+
+	if c.BanEnabled() && (c.BanWindow <= 0 || c.BanDuration <= 0) {
+		return fmt.Errorf("%w: ban window and duration must be positive", ErrInvalidConfig)
+	}
+
+	if c.BanTarpit < 0 {
+		return fmt.Errorf("%w: ban tarpit is negative", ErrInvalidConfig)
+	}
+
+	// Tarpitted responses would time out instead of being answered
+	if c.WriteTimeout > 0 && c.BanTarpit >= c.WriteTimeout {
+		return fmt.Errorf("%w: ban tarpit must be shorter than the write timeout", ErrInvalidConfig)
+	}
+
+	if c.ReadyMaxAge < 0 {
+		return fmt.Errorf("%w: ready max age is negative", ErrInvalidConfig)
+	}
+
+	if err := c.validateTimeouts(); err != nil {
+		return err
+	}
+
+	if err := c.validateLog(); err != nil {
+		return err
+	}
+
+	// Metrics must not be public
+	if c.Metrics && c.AdminAddr == "" {
+		return fmt.Errorf("%w: metrics require an admin address", ErrInvalidConfig)
+	}
+
+	if err := c.validateAnalytics(); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+func (c *Config) validateTimeouts() error {
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read timeout", c.ReadTimeout},
+		{"write timeout", c.WriteTimeout},
+		{"idle timeout", c.IdleTimeout},
+		{"read header timeout", c.ReadHeaderTimeout},
+		{"request timeout", c.RequestTimeout},
+		{"shutdown timeout", c.ShutdownTimeout},
+	} {
+		if timeout.value < 0 {
+			return fmt.Errorf("%w: %s is negative", ErrInvalidConfig, timeout.name)
+		}
+	}
+
+	// Headers are part of the request, so they can't take longer to read
+	if c.ReadTimeout > 0 && c.ReadHeaderTimeout > c.ReadTimeout {
+		return fmt.Errorf("%w: read header timeout is longer than the read timeout", ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+func (c *Config) validateAnalytics() error {
+	if !c.Analytics {
+		return nil
+	}
+
+	// The top looked up resources must not be public
+	if c.AdminAddr == "" {
+		return fmt.Errorf("%w: analytics require an admin address", ErrInvalidConfig)
+	}
+
+	if c.AnalyticsTop < 1 {
+		return fmt.Errorf("%w: analytics top must be at least 1", ErrInvalidConfig)
+	}
+
+	if c.AnalyticsWindow <= 0 {
+		return fmt.Errorf("%w: analytics window must be positive", ErrInvalidConfig)
+	}
+
+	if c.AnalyticsFile != "" && c.AnalyticsFlushInterval <= 0 {
+		return fmt.Errorf("%w: analytics flush interval must be positive", ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+func (c *Config) validateLog() error {
+	switch c.LogFormat {
+	case "", LogFormatJSON, LogFormatText:
+	default:
+		return fmt.Errorf("%w: invalid log format %q", ErrInvalidConfig, c.LogFormat)
+	}
+
+	switch c.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("%w: invalid log level %q", ErrInvalidConfig, c.LogLevel)
+	}
+
+	switch c.LogLookups {
+	case "", LogLookupsPlain, LogLookupsHash, LogLookupsNone:
+	default:
+		return fmt.Errorf("%w: invalid log lookups mode %q", ErrInvalidConfig, c.LogLookups)
+	}
+
+	if c.LogMaxSize < 0 || c.LogMaxBackups < 0 {
+		return fmt.Errorf("%w: log max size and backups can't be negative", ErrInvalidConfig)
+	}
+
+	if c.LogOutput == LogOutputSyslog {
+		if _, _, err := c.GetLogSyslogAddr(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetLogSyslogAddr splits the syslog address, given as "unix:/path" or
+// "udp:host:port", into its network and address.
+func (c *Config) GetLogSyslogAddr() (network, addr string, err error) {
+	network, addr, ok := strings.Cut(c.LogSyslogAddr, ":")
+	if !ok || addr == "" {
+		return "", "", fmt.Errorf("%w: invalid syslog address %q", ErrInvalidConfig, c.LogSyslogAddr)
+	}
+
+	switch network {
+	case "unix":
+	case "udp":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return "", "", fmt.Errorf("%w: invalid syslog address %q: %w", ErrInvalidConfig, c.LogSyslogAddr, err)
+		}
+	default:
+		return "", "", fmt.Errorf("%w: syslog network must be unix or udp, got %q", ErrInvalidConfig, network)
+	}
+
+	return network, addr, nil
+}
+
+// GetTrustedProxies parses the CIDRs of the proxies whose forwarding
+// headers are trusted. Plain IPs are also accepted.
+func (c *Config) GetTrustedProxies() ([]netip.Prefix, error) {
+	return parsePrefixes(c.TrustedProxies)
+}
+
+// GetClientIPHeader returns the header the trusted proxies set to the
+// client IP, which must be Forwarded, X-Forwarded-For or X-Real-IP. It
+// defaults to X-Forwarded-For.
+func (c *Config) GetClientIPHeader() (string, error) {
+	if c.ClientIPHeader == "" {
+		return DefaultClientIPHeader, nil
+	}
+
+	header := http.CanonicalHeaderKey(c.ClientIPHeader)
+
+	switch header {
+	case clientip.HeaderForwarded, clientip.HeaderXForwardedFor, clientip.HeaderXRealIP:
+		return header, nil
+	default:
+		return "", fmt.Errorf("%w: client IP header must be Forwarded, X-Forwarded-For or X-Real-IP, got %q", ErrInvalidConfig, c.ClientIPHeader)
+	}
+}
+
+// BanEnabled reports whether clients with too many misses are banned.
+func (c *Config) BanEnabled() bool {
+	return c.BanThreshold > 0
+}
+
+// RateLimitEnabled reports whether webfinger requests are rate limited.
+func (c *Config) RateLimitEnabled() bool {
+	return c.RateLimit > 0
+}
+
+// GetRateLimitAllowlist parses the CIDRs of the clients that are never
+// rate limited. Plain IPs are also accepted.
+func (c *Config) GetRateLimitAllowlist() ([]netip.Prefix, error) {
+	return parsePrefixes(c.RateLimitAllowlist)
+}
+
+// GetResourceMaxAges parses the per resource cache max ages, which are
+// given as "resource=duration" pairs. Resources are keyed by their subject,
+// so bob@example.com applies to acct:bob@example.com.
+func (c *Config) GetResourceMaxAges() (map[string]time.Duration, error) {
+	maxAges := make(map[string]time.Duration, len(c.CacheResourceMaxAge))
+
+	for _, v := range c.CacheResourceMaxAge {
+		// Split on the last "=" since resources may contain one
+		i := strings.LastIndex(v, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%w: invalid resource max age %q, expected resource=duration", ErrInvalidConfig, v)
+		}
+
+		maxAge, err := time.ParseDuration(v[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid resource max age %q: %w", ErrInvalidConfig, v, err)
+		}
+
+		if maxAge < 0 {
+			return nil, fmt.Errorf("%w: resource max age %q is negative", ErrInvalidConfig, v)
+		}
+
+		subject, err := webfingers.ParseSubject(v[:i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid resource max age %q: %w", ErrInvalidConfig, v, err)
+		}
+
+		maxAges[subject] = maxAge
+	}
+
+	return maxAges, nil
+}
+
+// parsePrefixes parses a list of CIDRs or IPs, which may also be
+// comma separated.
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+
+			if !strings.Contains(v, "/") {
+				ip, err := netip.ParseAddr(v)
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid CIDR %q: %w", ErrInvalidConfig, v, err)
+				}
+
+				prefixes = append(prefixes, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+
+				continue
+			}
+
+			prefix, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid CIDR %q: %w", ErrInvalidConfig, v, err)
+			}
+
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+
+	return prefixes, nil
+}
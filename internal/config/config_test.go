@@ -1,7 +1,11 @@
 package config_test
 
 import (
+	"net/netip"
+	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"git.maronato.dev/maronato/finger/internal/config"
 )
@@ -27,6 +31,14 @@ func TestConfig_GetAddr(t *testing.T) {
 			},
 			want: "example.com:1234",
 		},
+		{
+			name: "unix socket",
+			cfg: &config.Config{
+				Host: "unix:/run/finger.sock",
+				Port: "1234",
+			},
+			want: "unix:/run/finger.sock",
+		},
 	}
 
 	for _, tt := range tests {
@@ -98,6 +110,604 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "negative cache max age",
+			cfg: &config.Config{
+				Host:        config.DefaultHost,
+				Port:        config.DefaultPort,
+				URNPath:     config.DefaultURNPath,
+				FingerPath:  config.DefaultFingerPath,
+				CacheMaxAge: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid resource max age",
+			cfg: &config.Config{
+				Host:                config.DefaultHost,
+				Port:                config.DefaultPort,
+				URNPath:             config.DefaultURNPath,
+				FingerPath:          config.DefaultFingerPath,
+				CacheResourceMaxAge: []string{"acct:user@example.com"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative compress min size",
+			cfg: &config.Config{
+				Host:            config.DefaultHost,
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				CompressMinSize: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "tls cert without key",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				TLSCert:    "cert.pem",
+			},
+			wantErr: true,
+		},
+		{
+			name: "redirect port without tls",
+			cfg: &config.Config{
+				Host:             config.DefaultHost,
+				Port:             config.DefaultPort,
+				URNPath:          config.DefaultURNPath,
+				FingerPath:       config.DefaultFingerPath,
+				HTTPRedirectPort: "80",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid tls",
+			cfg: &config.Config{
+				Host:             config.DefaultHost,
+				Port:             config.DefaultPort,
+				URNPath:          config.DefaultURNPath,
+				FingerPath:       config.DefaultFingerPath,
+				TLSCert:          "cert.pem",
+				TLSKey:           "key.pem",
+				HTTPRedirectPort: "80",
+			},
+			wantErr: false,
+		},
+		{
+			name: "empty socket path",
+			cfg: &config.Config{
+				Host:       "unix:",
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				SocketMode: config.DefaultSocketMode,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid socket mode",
+			cfg: &config.Config{
+				Host:       "unix:/run/finger.sock",
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				SocketMode: "rw-rw----",
+			},
+			wantErr: true,
+		},
+		{
+			name: "redirect port with unix socket",
+			cfg: &config.Config{
+				Host:             "unix:/run/finger.sock",
+				Port:             config.DefaultPort,
+				URNPath:          config.DefaultURNPath,
+				FingerPath:       config.DefaultFingerPath,
+				SocketMode:       config.DefaultSocketMode,
+				TLSCert:          "cert.pem",
+				TLSKey:           "key.pem",
+				HTTPRedirectPort: "80",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid unix socket",
+			cfg: &config.Config{
+				Host:       "unix:/run/finger.sock",
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				SocketMode: "0600",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid admin addr",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				AdminAddr:  "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid admin addr",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				AdminAddr:  "localhost:9090",
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative rate limit",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				RateLimit:  -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "rate limit without burst",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				RateLimit:  10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid rate limit allowlist",
+			cfg: &config.Config{
+				Host:               config.DefaultHost,
+				Port:               config.DefaultPort,
+				URNPath:            config.DefaultURNPath,
+				FingerPath:         config.DefaultFingerPath,
+				RateLimitAllowlist: []string{"10.0.0.0/33"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid rate limit",
+			cfg: &config.Config{
+				Host:               config.DefaultHost,
+				Port:               config.DefaultPort,
+				URNPath:            config.DefaultURNPath,
+				FingerPath:         config.DefaultFingerPath,
+				RateLimit:          10,
+				RateLimitBurst:     20,
+				RateLimitAllowlist: []string{"10.0.0.0/8", "::1"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid trusted proxies",
+			cfg: &config.Config{
+				Host:           config.DefaultHost,
+				Port:           config.DefaultPort,
+				URNPath:        config.DefaultURNPath,
+				FingerPath:     config.DefaultFingerPath,
+				TrustedProxies: []string{"proxy.local"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid trusted proxies",
+			cfg: &config.Config{
+				Host:           config.DefaultHost,
+				Port:           config.DefaultPort,
+				URNPath:        config.DefaultURNPath,
+				FingerPath:     config.DefaultFingerPath,
+				TrustedProxies: []string{"10.0.0.0/8", "fd00::/8"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "client IP header",
+			cfg: &config.Config{
+				Host:           config.DefaultHost,
+				Port:           config.DefaultPort,
+				URNPath:        config.DefaultURNPath,
+				FingerPath:     config.DefaultFingerPath,
+				TrustedProxies: []string{"10.0.0.0/8"},
+				ClientIPHeader: "x-real-ip",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid client IP header",
+			cfg: &config.Config{
+				Host:           config.DefaultHost,
+				Port:           config.DefaultPort,
+				URNPath:        config.DefaultURNPath,
+				FingerPath:     config.DefaultFingerPath,
+				TrustedProxies: []string{"10.0.0.0/8"},
+				ClientIPHeader: "CF-Connecting-IP",
+			},
+			wantErr: true,
+		},
+		{
+			name: "trusted unix socket",
+			cfg: &config.Config{
+				Host:            "unix:/run/finger.sock",
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				SocketMode:      config.DefaultSocketMode,
+				TrustUnixSocket: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "trusted unix socket without one",
+			cfg: &config.Config{
+				Host:            config.DefaultHost,
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				TrustUnixSocket: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative ban threshold",
+			cfg: &config.Config{
+				Host:         config.DefaultHost,
+				Port:         config.DefaultPort,
+				URNPath:      config.DefaultURNPath,
+				FingerPath:   config.DefaultFingerPath,
+				BanThreshold: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "ban without window",
+			cfg: &config.Config{
+				Host:         config.DefaultHost,
+				Port:         config.DefaultPort,
+				URNPath:      config.DefaultURNPath,
+				FingerPath:   config.DefaultFingerPath,
+				BanThreshold: 10,
+				BanDuration:  time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative ban tarpit",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				BanTarpit:  -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "metrics without admin address",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				Metrics:    true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "metrics with admin address",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				Metrics:    true,
+				AdminAddr:  "localhost:9090",
+			},
+			wantErr: false,
+		},
+		{
+			name: "ban tarpit over the write timeout",
+			cfg: &config.Config{
+				Host:         config.DefaultHost,
+				Port:         config.DefaultPort,
+				URNPath:      config.DefaultURNPath,
+				FingerPath:   config.DefaultFingerPath,
+				BanTarpit:    10 * time.Second,
+				WriteTimeout: 10 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid ban",
+			cfg: &config.Config{
+				Host:         config.DefaultHost,
+				Port:         config.DefaultPort,
+				URNPath:      config.DefaultURNPath,
+				FingerPath:   config.DefaultFingerPath,
+				BanThreshold: 10,
+				BanWindow:    time.Minute,
+				BanDuration:  time.Hour,
+				BanTarpit:    time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid log format",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				LogFormat:  "xml",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid log level",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				LogLevel:   "verbose",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid log lookups",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				LogLookups: "encrypt",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative log max size",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				LogMaxSize: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid syslog addr",
+			cfg: &config.Config{
+				Host:          config.DefaultHost,
+				Port:          config.DefaultPort,
+				URNPath:       config.DefaultURNPath,
+				FingerPath:    config.DefaultFingerPath,
+				LogOutput:     config.LogOutputSyslog,
+				LogSyslogAddr: "tcp:localhost:514",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid log",
+			cfg: &config.Config{
+				Host:          config.DefaultHost,
+				Port:          config.DefaultPort,
+				URNPath:       config.DefaultURNPath,
+				FingerPath:    config.DefaultFingerPath,
+				LogFormat:     config.LogFormatText,
+				LogLevel:      "warn",
+				LogOutput:     config.LogOutputSyslog,
+				LogSyslogAddr: "udp:localhost:514",
+				LogLookups:    config.LogLookupsHash,
+				LogLookupsKey: "secret",
+			},
+			wantErr: false,
+		},
+		{
+			name: "admin API without admin address",
+			cfg: &config.Config{
+				Host:            config.DefaultHost,
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				AdminTokensFile: "tokens",
+			},
+			wantErr: true,
+		},
+		{
+			name: "admin persist without admin API",
+			cfg: &config.Config{
+				Host:         config.DefaultHost,
+				Port:         config.DefaultPort,
+				URNPath:      config.DefaultURNPath,
+				FingerPath:   config.DefaultFingerPath,
+				AdminAddr:    "localhost:9090",
+				AdminPersist: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "admin API",
+			cfg: &config.Config{
+				Host:            config.DefaultHost,
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				AdminAddr:       "localhost:9090",
+				AdminTokensFile: "tokens",
+				AdminPersist:    true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "audit file is the log file",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				LogOutput:  "finger.log",
+				AuditFile:  "finger.log",
+			},
+			wantErr: true,
+		},
+		{
+			name: "audit file",
+			cfg: &config.Config{
+				Host:       config.DefaultHost,
+				Port:       config.DefaultPort,
+				URNPath:    config.DefaultURNPath,
+				FingerPath: config.DefaultFingerPath,
+				LogOutput:  "finger.log",
+				AuditFile:  "audit.jsonl",
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative ready max age",
+			cfg: &config.Config{
+				Host:        config.DefaultHost,
+				Port:        config.DefaultPort,
+				URNPath:     config.DefaultURNPath,
+				FingerPath:  config.DefaultFingerPath,
+				ReadyMaxAge: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid timeouts",
+			cfg: &config.Config{
+				Host:              config.DefaultHost,
+				Port:              config.DefaultPort,
+				URNPath:           config.DefaultURNPath,
+				FingerPath:        config.DefaultFingerPath,
+				ReadTimeout:       time.Minute,
+				WriteTimeout:      time.Minute,
+				IdleTimeout:       time.Minute,
+				ReadHeaderTimeout: time.Second,
+				RequestTimeout:    time.Minute,
+				ShutdownTimeout:   time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative write timeout",
+			cfg: &config.Config{
+				Host:         config.DefaultHost,
+				Port:         config.DefaultPort,
+				URNPath:      config.DefaultURNPath,
+				FingerPath:   config.DefaultFingerPath,
+				WriteTimeout: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative shutdown timeout",
+			cfg: &config.Config{
+				Host:            config.DefaultHost,
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				ShutdownTimeout: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "read header timeout longer than read timeout",
+			cfg: &config.Config{
+				Host:              config.DefaultHost,
+				Port:              config.DefaultPort,
+				URNPath:           config.DefaultURNPath,
+				FingerPath:        config.DefaultFingerPath,
+				ReadTimeout:       time.Second,
+				ReadHeaderTimeout: time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "analytics without top",
+			cfg: &config.Config{
+				Host:            config.DefaultHost,
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				AdminAddr:       "localhost:9090",
+				Analytics:       true,
+				AnalyticsWindow: time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name: "analytics without window",
+			cfg: &config.Config{
+				Host:         config.DefaultHost,
+				Port:         config.DefaultPort,
+				URNPath:      config.DefaultURNPath,
+				FingerPath:   config.DefaultFingerPath,
+				AdminAddr:    "localhost:9090",
+				Analytics:    true,
+				AnalyticsTop: 10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "analytics file without interval",
+			cfg: &config.Config{
+				Host:            config.DefaultHost,
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				AdminAddr:       "localhost:9090",
+				Analytics:       true,
+				AnalyticsTop:    10,
+				AnalyticsWindow: time.Hour,
+				AnalyticsFile:   "analytics.csv",
+			},
+			wantErr: true,
+		},
+		{
+			name: "analytics without admin address",
+			cfg: &config.Config{
+				Host:            config.DefaultHost,
+				Port:            config.DefaultPort,
+				URNPath:         config.DefaultURNPath,
+				FingerPath:      config.DefaultFingerPath,
+				Analytics:       true,
+				AnalyticsTop:    10,
+				AnalyticsWindow: time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid analytics",
+			cfg: &config.Config{
+				Host:                   config.DefaultHost,
+				Port:                   config.DefaultPort,
+				URNPath:                config.DefaultURNPath,
+				FingerPath:             config.DefaultFingerPath,
+				AdminAddr:              "localhost:9090",
+				Analytics:              true,
+				AnalyticsTop:           10,
+				AnalyticsWindow:        time.Hour,
+				AnalyticsFile:          "analytics.csv",
+				AnalyticsFlushInterval: time.Minute,
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid",
 			cfg: &config.Config{
@@ -122,3 +732,248 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_GetResourceMaxAges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{
+			name:   "empty",
+			values: nil,
+			want:   map[string]time.Duration{},
+		},
+		{
+			name:   "parses pairs",
+			values: []string{"acct:user@example.com=1h", "https://example.com/?a=b=30s"},
+			want: map[string]time.Duration{
+				"acct:user@example.com":    time.Hour,
+				"https://example.com/?a=b": 30 * time.Second,
+			},
+		},
+		{
+			name:   "keys by subject",
+			values: []string{"bob@example.com=1h"},
+			want:   map[string]time.Duration{"acct:bob@example.com": time.Hour},
+		},
+		{
+			name:    "invalid resource",
+			values:  []string{"not a resource=1h"},
+			wantErr: true,
+		},
+		{
+			name:    "missing duration",
+			values:  []string{"acct:user@example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "missing resource",
+			values:  []string{"=1h"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			values:  []string{"acct:user@example.com=forever"},
+			wantErr: true,
+		},
+		{
+			name:    "negative duration",
+			values:  []string{"acct:user@example.com=-1h"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.NewConfig()
+			cfg.CacheResourceMaxAge = tc.values
+
+			got, err := cfg.GetResourceMaxAges()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Config.GetResourceMaxAges() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Config.GetResourceMaxAges() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfig_GetSocketMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mode    string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{
+			name: "default",
+			mode: config.DefaultSocketMode,
+			want: 0o660,
+		},
+		{
+			name: "without leading zero",
+			mode: "777",
+			want: 0o777,
+		},
+		{
+			name:    "not octal",
+			mode:    "0999",
+			wantErr: true,
+		},
+		{
+			name:    "too large",
+			mode:    "01777",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.NewConfig()
+			cfg.SocketMode = tc.mode
+
+			got, err := cfg.GetSocketMode()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Config.GetSocketMode() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("Config.GetSocketMode() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfig_GetRateLimitAllowlist(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		want      []netip.Prefix
+		wantErr   bool
+	}{
+		{
+			name:      "empty",
+			allowlist: nil,
+			want:      nil,
+		},
+		{
+			name:      "cidrs and ips",
+			allowlist: []string{"10.1.2.3/8", "192.0.2.1", "2001:db8::/32"},
+			want: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("192.0.2.1/32"),
+				netip.MustParsePrefix("2001:db8::/32"),
+			},
+		},
+		{
+			name:      "comma separated",
+			allowlist: []string{"10.0.0.0/8, 127.0.0.1"},
+			want: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("127.0.0.1/32"),
+			},
+		},
+		{
+			name:      "invalid ip",
+			allowlist: []string{"localhost"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid cidr",
+			allowlist: []string{"10.0.0.0/40"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &config.Config{RateLimitAllowlist: tc.allowlist}
+
+			got, err := cfg.GetRateLimitAllowlist()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Config.GetRateLimitAllowlist() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Config.GetRateLimitAllowlist() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfig_GetLogSyslogAddr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		addr        string
+		wantNetwork string
+		wantAddr    string
+		wantErr     bool
+	}{
+		{
+			name:        "unix",
+			addr:        "unix:/dev/log",
+			wantNetwork: "unix",
+			wantAddr:    "/dev/log",
+		},
+		{
+			name:        "udp",
+			addr:        "udp:[::1]:514",
+			wantNetwork: "udp",
+			wantAddr:    "[::1]:514",
+		},
+		{
+			name:    "udp without port",
+			addr:    "udp:localhost",
+			wantErr: true,
+		},
+		{
+			name:    "missing network",
+			addr:    "/dev/log",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported network",
+			addr:    "tcp:localhost:514",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &config.Config{LogSyslogAddr: tc.addr}
+
+			network, addr, err := cfg.GetLogSyslogAddr()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Config.GetLogSyslogAddr() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if network != tc.wantNetwork || addr != tc.wantAddr {
+				t.Errorf("Config.GetLogSyslogAddr() = %v, %v, want %v, %v", network, addr, tc.wantNetwork, tc.wantAddr)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfigFile is returned when the config file can't be parsed.
+var ErrInvalidConfigFile = errors.New("invalid config file")
+
+// configFileDelimiter joins the keys of nested maps into flag names, so
+// `log: {format: text}` sets `log-format`.
+const configFileDelimiter = "-"
+
+// ParseFile parses a YAML or JSON config file, calling set with the flag
+// name and value of every setting in it. Lists set the flag once for each
+// item.
+func ParseFile(r io.Reader, set func(name, value string) error) error {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		// Empty files have no settings
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %w", ErrInvalidConfigFile, err)
+	}
+
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	root := resolveAlias(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%w: expected a map of settings", ErrInvalidConfigFile)
+	}
+
+	return parseMapping(root, "", set)
+}
+
+func parseMapping(node *yaml.Node, prefix string, set func(name, value string) error) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		if prefix != "" {
+			name = prefix + configFileDelimiter + name
+		}
+
+		value := resolveAlias(node.Content[i+1])
+
+		switch value.Kind {
+		case yaml.MappingNode:
+			if err := parseMapping(value, name, set); err != nil {
+				return err
+			}
+		case yaml.SequenceNode:
+			for _, item := range value.Content {
+				item = resolveAlias(item)
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("%w: %s: line %d: expected a list of values", ErrInvalidConfigFile, name, item.Line)
+				}
+
+				if err := set(name, item.Value); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := set(name, value.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveAlias returns the node an alias points to, or the node itself.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	return node
+}
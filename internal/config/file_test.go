@@ -0,0 +1,121 @@
+package config_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+)
+
+func TestParseFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		want    [][2]string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			file: "",
+			want: nil,
+		},
+		{
+			name: "yaml",
+			file: `
+# Listen on all interfaces
+host: 0.0.0.0
+port: 8080
+compress: false
+cache-max-age: 1h
+trusted-proxies:
+  - 10.0.0.0/8
+  - 192.168.0.0/16
+`,
+			want: [][2]string{
+				{"host", "0.0.0.0"},
+				{"port", "8080"},
+				{"compress", "false"},
+				{"cache-max-age", "1h"},
+				{"trusted-proxies", "10.0.0.0/8"},
+				{"trusted-proxies", "192.168.0.0/16"},
+			},
+		},
+		{
+			name: "json",
+			file: `{"port": 8080, "rate-limit": 1.5, "trusted-proxies": ["10.0.0.0/8"]}`,
+			want: [][2]string{
+				{"port", "8080"},
+				{"rate-limit", "1.5"},
+				{"trusted-proxies", "10.0.0.0/8"},
+			},
+		},
+		{
+			name: "nested",
+			file: `
+log:
+  format: text
+  max-size: 10
+`,
+			want: [][2]string{
+				{"log-format", "text"},
+				{"log-max-size", "10"},
+			},
+		},
+		{
+			name: "aliases",
+			file: `
+ban-window: &window 5m
+analytics-window: *window
+`,
+			want: [][2]string{
+				{"ban-window", "5m"},
+				{"analytics-window", "5m"},
+			},
+		},
+		{
+			name:    "not a map",
+			file:    "- port",
+			wantErr: true,
+		},
+		{
+			name:    "list of maps",
+			file:    "trusted-proxies: [{cidr: 10.0.0.0/8}]",
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			file:    "port: [8080",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got [][2]string
+
+			err := config.ParseFile(strings.NewReader(tc.file), func(name, value string) error {
+				got = append(got, [2]string{name, value})
+
+				return nil
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseFile() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, config.ErrInvalidConfigFile) {
+				t.Errorf("ParseFile() error = %v, want %v", err, config.ErrInvalidConfigFile)
+			}
+
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseFile() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
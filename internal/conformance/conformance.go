@@ -0,0 +1,371 @@
+// Package conformance checks webfinger servers against RFC 7033.
+package conformance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"git.maronato.dev/maronato/finger/client"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+// maxBodySize is the maximum size of the responses read from servers.
+const maxBodySize = 1 << 20
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// StatusPass means the server follows the spec.
+	StatusPass Status = "pass"
+	// StatusWarn means the server works but doesn't follow a recommendation.
+	StatusWarn Status = "warn"
+	// StatusFail means the server breaks a requirement of the spec.
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of a check with an explanation.
+type Result struct {
+	Check   string `json:"check"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Suite checks a webfinger server.
+type Suite struct {
+	// URL is the base URL of the server, like https://example.com.
+	URL string
+	// Resource is a resource the server knows about.
+	Resource string
+	// HTTPURL is the plain HTTP base URL used to check for redirects to
+	// HTTPS. It defaults to URL with the http scheme.
+	HTTPURL string
+	// Client makes the requests. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// response is a response read in full.
+type response struct {
+	*http.Response
+	body []byte
+}
+
+// Run runs every check in order.
+func (s *Suite) Run(ctx context.Context) []Result {
+	if s.Client == nil {
+		s.Client = http.DefaultClient
+	}
+
+	results := []Result{s.checkHTTPS()}
+
+	// Most checks need the JRD of the known resource
+	res, err := s.get(ctx, http.MethodGet, s.Resource, nil, nil)
+	if err != nil {
+		return append(results, Result{"lookup", StatusFail, err.Error()})
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return append(results, Result{"lookup", StatusFail, fmt.Sprintf("looking up %s responded with %d instead of 200", s.Resource, res.StatusCode)})
+	}
+
+	finger, jrdResult := s.checkJRD(res)
+
+	results = append(results,
+		checkContentType(res),
+		jrdResult,
+		checkCORS(res),
+		s.checkMissingResource(ctx),
+		s.checkUnknownResource(ctx),
+		s.checkRelFilter(ctx, finger),
+		s.checkHead(ctx, res),
+		s.checkOptions(ctx),
+		s.checkHTTPRedirect(ctx),
+	)
+
+	return results
+}
+
+// checkHTTPS checks that the server is queried over HTTPS (section 4).
+func (s *Suite) checkHTTPS() Result {
+	const check = "https"
+
+	if strings.HasPrefix(s.URL, "https://") {
+		return Result{check, StatusPass, "the server is queried over HTTPS"}
+	}
+
+	return Result{check, StatusFail, "webfinger queries must be made over HTTPS"}
+}
+
+// checkContentType checks the media type of the JRD (section 10.2).
+func checkContentType(res *response) Result {
+	const check = "content-type"
+
+	contentType := res.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "application/jrd+json":
+		return Result{check, StatusPass, "responses are application/jrd+json"}
+	case "application/json":
+		return Result{check, StatusWarn, "responses are application/json instead of application/jrd+json"}
+	default:
+		return Result{check, StatusFail, fmt.Sprintf("responses are %q instead of application/jrd+json", contentType)}
+	}
+}
+
+// checkJRD checks that the response is a valid JRD for the resource
+// (section 4.4), and returns it.
+func (s *Suite) checkJRD(res *response) (*webfingers.WebFinger, Result) {
+	const check = "jrd"
+
+	finger, err := client.DecodeJRD(res.body)
+	if err != nil {
+		return nil, Result{check, StatusFail, err.Error()}
+	}
+
+	if subject, err := webfingers.ParseSubject(s.Resource); err == nil && finger.Subject != subject {
+		return finger, Result{check, StatusWarn, fmt.Sprintf("the subject %s is not the resource %s", finger.Subject, subject)}
+	}
+
+	return finger, Result{check, StatusPass, "the response is a valid JRD"}
+}
+
+// checkCORS checks that browsers can read the responses (section 5).
+func checkCORS(res *response) Result {
+	const check = "cors"
+
+	switch origin := res.Header.Get("Access-Control-Allow-Origin"); origin {
+	case "*":
+		return Result{check, StatusPass, "responses can be read from any origin"}
+	case "":
+		return Result{check, StatusFail, "responses have no Access-Control-Allow-Origin header"}
+	default:
+		return Result{check, StatusWarn, fmt.Sprintf("responses can only be read from %s, the least restrictive * is recommended", origin)}
+	}
+}
+
+// checkMissingResource checks that queries without a resource are bad
+// requests (section 4.2).
+func (s *Suite) checkMissingResource(ctx context.Context) Result {
+	const check = "missing-resource"
+
+	res, err := s.get(ctx, http.MethodGet, "", nil, nil)
+	if err != nil {
+		return Result{check, StatusFail, err.Error()}
+	}
+
+	if res.StatusCode != http.StatusBadRequest {
+		return Result{check, StatusFail, fmt.Sprintf("queries without a resource responded with %d instead of 400", res.StatusCode)}
+	}
+
+	return Result{check, StatusPass, "queries without a resource respond with 400"}
+}
+
+// checkUnknownResource checks that unknown resources aren't found
+// (section 4.2).
+func (s *Suite) checkUnknownResource(ctx context.Context) Result {
+	const check = "unknown-resource"
+
+	res, err := s.get(ctx, http.MethodGet, unknownResource(s.Resource), nil, nil)
+	if err != nil {
+		return Result{check, StatusFail, err.Error()}
+	}
+
+	if res.StatusCode != http.StatusNotFound {
+		return Result{check, StatusFail, fmt.Sprintf("unknown resources responded with %d instead of 404", res.StatusCode)}
+	}
+
+	return Result{check, StatusPass, "unknown resources respond with 404"}
+}
+
+// checkRelFilter checks that the rel param picks the links of the
+// response (section 4.3).
+func (s *Suite) checkRelFilter(ctx context.Context, finger *webfingers.WebFinger) Result {
+	const check = "rel-filter"
+
+	if finger == nil || len(finger.Links) == 0 {
+		return Result{check, StatusWarn, fmt.Sprintf("%s has no links to filter", s.Resource)}
+	}
+
+	rel := finger.Links[0].Rel
+
+	res, err := s.get(ctx, http.MethodGet, s.Resource, []string{rel}, nil)
+	if err != nil {
+		return Result{check, StatusFail, err.Error()}
+	}
+
+	filtered, err := client.DecodeJRD(res.body)
+	if res.StatusCode != http.StatusOK || err != nil {
+		return Result{check, StatusFail, fmt.Sprintf("filtering by rel responded with %d and no valid JRD", res.StatusCode)}
+	}
+
+	// Properties are not filtered
+	if !reflect.DeepEqual(filtered.Properties, finger.Properties) {
+		return Result{check, StatusFail, "filtering by rel changed the properties"}
+	}
+
+	for _, l := range filtered.Links {
+		if l.Rel != rel {
+			return Result{check, StatusWarn, "the rel param is ignored, which is allowed but makes responses larger"}
+		}
+	}
+
+	if len(filtered.Links) == 0 {
+		return Result{check, StatusFail, fmt.Sprintf("filtering by rel %s removed all of its links", rel)}
+	}
+
+	return Result{check, StatusPass, "the rel param picks the links"}
+}
+
+// checkHead checks that HEAD requests respond like GET ones without a body.
+func (s *Suite) checkHead(ctx context.Context, get *response) Result {
+	const check = "head"
+
+	res, err := s.get(ctx, http.MethodHead, s.Resource, nil, nil)
+	if err != nil {
+		return Result{check, StatusFail, err.Error()}
+	}
+
+	switch {
+	case res.StatusCode != http.StatusOK:
+		return Result{check, StatusWarn, fmt.Sprintf("HEAD requests responded with %d instead of 200", res.StatusCode)}
+	case len(res.body) > 0:
+		return Result{check, StatusFail, "HEAD requests responded with a body"}
+	case res.Header.Get("Content-Type") != get.Header.Get("Content-Type"):
+		return Result{check, StatusWarn, "HEAD requests have a different content type than GET ones"}
+	}
+
+	return Result{check, StatusPass, "HEAD requests respond like GET ones"}
+}
+
+// checkOptions checks that CORS preflight requests are allowed.
+func (s *Suite) checkOptions(ctx context.Context) Result {
+	const check = "options"
+
+	res, err := s.get(ctx, http.MethodOptions, s.Resource, nil, http.Header{
+		"Origin":                        {"https://finger-check.example"},
+		"Access-Control-Request-Method": {http.MethodGet},
+	})
+	if err != nil {
+		return Result{check, StatusFail, err.Error()}
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 || res.Header.Get("Access-Control-Allow-Origin") == "" {
+		return Result{check, StatusWarn, fmt.Sprintf("CORS preflight requests responded with %d and no Access-Control-Allow-Origin header", res.StatusCode)}
+	}
+
+	return Result{check, StatusPass, "CORS preflight requests are allowed"}
+}
+
+// checkHTTPRedirect checks that plain HTTP queries are redirected to HTTPS
+// instead of being answered.
+func (s *Suite) checkHTTPRedirect(ctx context.Context) Result {
+	const check = "http-redirect"
+
+	base := s.HTTPURL
+	if base == "" {
+		base = "http://" + strings.TrimPrefix(strings.TrimPrefix(s.URL, "https://"), "http://")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint(base, s.Resource, nil), http.NoBody)
+	if err != nil {
+		return Result{check, StatusFail, err.Error()}
+	}
+
+	// Look at the redirect instead of following it
+	c := *s.Client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	res, err := c.Do(req)
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return Result{check, StatusPass, "plain HTTP isn't served"}
+	case err != nil:
+		// Timeouts and other errors don't say whether queries are redirected
+		return Result{check, StatusWarn, fmt.Sprintf("plain HTTP couldn't be checked: %s", err)}
+	}
+	defer res.Body.Close()
+
+	location, _ := res.Location()
+
+	switch {
+	case location != nil && location.Scheme == "https":
+		return Result{check, StatusPass, "plain HTTP queries are redirected to HTTPS"}
+	case location != nil:
+		return Result{check, StatusFail, fmt.Sprintf("plain HTTP queries are redirected to %s instead of HTTPS", location)}
+	case res.StatusCode == http.StatusOK:
+		return Result{check, StatusWarn, "plain HTTP queries are answered instead of redirected to HTTPS"}
+	default:
+		return Result{check, StatusPass, fmt.Sprintf("plain HTTP queries respond with %d", res.StatusCode)}
+	}
+}
+
+// get requests the webfinger endpoint and reads the response.
+func (s *Suite) get(ctx context.Context, method, resource string, rels []string, header http.Header) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint(s.URL, resource, rels), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodySize))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	return &response{Response: res, body: body}, nil
+}
+
+// endpoint returns the webfinger URL of the server for the resource and rels.
+func endpoint(base, resource string, rels []string) string {
+	q := url.Values{}
+	if resource != "" {
+		q.Set("resource", resource)
+	}
+
+	if len(rels) > 0 {
+		q["rel"] = rels
+	}
+
+	u := strings.TrimSuffix(base, "/") + "/.well-known/webfinger"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	return u
+}
+
+// unknownResource returns a random resource on the same host as resource.
+func unknownResource(resource string) string {
+	b := make([]byte, 8) //nolint:gomnd // Enough to never exist
+	_, _ = rand.Read(b)
+
+	host, err := client.Host(resource)
+	if err != nil {
+		host = "example.com"
+	}
+
+	return fmt.Sprintf("acct:finger-check-%s@%s", hex.EncodeToString(b), host)
+}
@@ -0,0 +1,293 @@
+package conformance_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/conformance"
+	"git.maronato.dev/maronato/finger/internal/server"
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+const resource = "acct:alice@example.com"
+
+// compliantHandler is a webfinger server that follows every check.
+func compliantHandler() http.Handler {
+	const (
+		full     = `{"subject":"acct:alice@example.com","links":[{"rel":"self","href":"https://example.com/alice"},{"rel":"profile","href":"https://example.com/@alice"}]}`
+		filtered = `{"subject":"acct:alice@example.com","links":[{"rel":"self","href":"https://example.com/alice"}]}`
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		q := r.URL.Query()
+
+		switch q.Get("resource") {
+		case "":
+			w.WriteHeader(http.StatusBadRequest)
+		case resource:
+			w.Header().Set("Content-Type", "application/jrd+json")
+
+			if r.Method == http.MethodHead {
+				return
+			}
+
+			if q.Get("rel") == "self" {
+				_, _ = w.Write([]byte(filtered))
+			} else {
+				_, _ = w.Write([]byte(full))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// sloppyHandler is a webfinger server that answers everything with the
+// same JSON.
+func sloppyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "https://example.com")
+		_, _ = w.Write([]byte(`{"subject":"acct:bob@example.com","links":[{"rel":"self","href":"https://example.com/bob"}]}`))
+	})
+}
+
+// fingerHandler is this server's own webfinger handler.
+func fingerHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	fingers := webfingers.WebFingers{
+		resource: {
+			Subject: resource,
+			Links: []webfingers.Link{
+				{Rel: "self", Href: "https://example.com/alice"},
+				{Rel: "profile", Href: "https://example.com/@alice"},
+			},
+		},
+	}
+
+	h, err := server.WebfingerHandler(config.NewConfig(), store.New(fingers, nil))
+	if err != nil {
+		t.Fatalf("error creating handler: %v", err)
+	}
+
+	return h
+}
+
+func TestSuite_Run(t *testing.T) {
+	t.Parallel()
+
+	// Plain HTTP server that redirects to HTTPS
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com"+r.URL.RequestURI(), http.StatusMovedPermanently)
+	}))
+	t.Cleanup(redirect.Close)
+
+	// Plain HTTP server that answers queries
+	plain := httptest.NewServer(sloppyHandler())
+	t.Cleanup(plain.Close)
+
+	tests := []struct {
+		name    string
+		handler func(t *testing.T) http.Handler
+		httpURL string
+		want    map[string]conformance.Status
+	}{
+		{
+			name:    "compliant server",
+			handler: func(*testing.T) http.Handler { return compliantHandler() },
+			httpURL: redirect.URL,
+			want: map[string]conformance.Status{
+				"https":            conformance.StatusPass,
+				"content-type":     conformance.StatusPass,
+				"jrd":              conformance.StatusPass,
+				"cors":             conformance.StatusPass,
+				"missing-resource": conformance.StatusPass,
+				"unknown-resource": conformance.StatusPass,
+				"rel-filter":       conformance.StatusPass,
+				"head":             conformance.StatusPass,
+				"options":          conformance.StatusPass,
+				"http-redirect":    conformance.StatusPass,
+			},
+		},
+		{
+			name:    "sloppy server",
+			handler: func(*testing.T) http.Handler { return sloppyHandler() },
+			httpURL: plain.URL,
+			want: map[string]conformance.Status{
+				"https":            conformance.StatusPass,
+				"content-type":     conformance.StatusWarn,
+				"jrd":              conformance.StatusWarn,
+				"cors":             conformance.StatusWarn,
+				"missing-resource": conformance.StatusFail,
+				"unknown-resource": conformance.StatusFail,
+				"rel-filter":       conformance.StatusPass,
+				"head":             conformance.StatusPass,
+				"options":          conformance.StatusPass,
+				"http-redirect":    conformance.StatusWarn,
+			},
+		},
+		{
+			name:    "finger server",
+			handler: fingerHandler,
+			want: map[string]conformance.Status{
+				"https":            conformance.StatusPass,
+				"content-type":     conformance.StatusPass,
+				"jrd":              conformance.StatusPass,
+				"cors":             conformance.StatusFail,
+				"missing-resource": conformance.StatusPass,
+				"unknown-resource": conformance.StatusPass,
+				"rel-filter":       conformance.StatusPass,
+				"head":             conformance.StatusPass,
+				"options":          conformance.StatusWarn,
+				"http-redirect":    conformance.StatusPass,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewTLSServer(tc.handler(t))
+			t.Cleanup(srv.Close)
+
+			s := &conformance.Suite{
+				URL:      srv.URL,
+				Resource: resource,
+				HTTPURL:  tc.httpURL,
+				Client:   srv.Client(),
+			}
+
+			got := make(map[string]conformance.Status)
+			for _, r := range s.Run(context.Background()) {
+				got[r.Check] = r.Status
+			}
+
+			for check, want := range tc.want {
+				if got[check] != want {
+					t.Errorf("check %s = %q, want %q", check, got[check], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSuite_Run_HTTPUnreachable(t *testing.T) {
+	t.Parallel()
+
+	// Nothing listens on the port of a closed listener
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+
+	closed.Close()
+
+	// And a blackholed port accepts connections but never answers
+	hanging, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+
+	t.Cleanup(func() { hanging.Close() })
+
+	go func() {
+		var conns []net.Conn
+
+		for {
+			conn, err := hanging.Accept()
+			if err != nil {
+				for _, c := range conns {
+					c.Close()
+				}
+
+				return
+			}
+
+			conns = append(conns, conn)
+		}
+	}()
+
+	tests := []struct {
+		name    string
+		httpURL string
+		want    conformance.Status
+	}{
+		{
+			name:    "refused",
+			httpURL: "http://" + closed.Addr().String(),
+			want:    conformance.StatusPass,
+		},
+		{
+			name:    "hanging",
+			httpURL: "http://" + hanging.Addr().String(),
+			want:    conformance.StatusWarn,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewTLSServer(compliantHandler())
+			t.Cleanup(srv.Close)
+
+			client := srv.Client()
+			client.Timeout = 200 * time.Millisecond
+
+			s := &conformance.Suite{
+				URL:      srv.URL,
+				Resource: resource,
+				HTTPURL:  tc.httpURL,
+				Client:   client,
+			}
+
+			for _, r := range s.Run(context.Background()) {
+				if r.Check == "http-redirect" && r.Status != tc.want {
+					t.Errorf("check http-redirect = %q, want %q: %s", r.Status, tc.want, r.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestSuite_Run_Unreachable(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(compliantHandler())
+	srv.Close()
+
+	s := &conformance.Suite{URL: "http" + srv.URL[len("https"):], Resource: resource}
+
+	results := s.Run(context.Background())
+
+	// Only the scheme and the lookup are checked
+	if len(results) != 2 {
+		t.Fatalf("Run() = %+v, want 2 results", results)
+	}
+
+	if results[0].Check != "https" || results[0].Status != conformance.StatusFail {
+		t.Errorf("first result = %+v, want a failed https check", results[0])
+	}
+
+	if results[1].Check != "lookup" || results[1].Status != conformance.StatusFail {
+		t.Errorf("second result = %+v, want a failed lookup", results[1])
+	}
+}
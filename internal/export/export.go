@@ -0,0 +1,302 @@
+// Package export writes the webfingers as static files, with the rewrite
+// rules web servers need to serve them.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+// Dir is the directory of the JRD files, relative to the export.
+const Dir = "webfinger"
+
+// Files of the rewrite rules, relative to the export.
+const (
+	NginxFile           = "nginx.conf"
+	ApacheFile          = ".htaccess"
+	NetlifyRedirectFile = "_redirects"
+	NetlifyHeadersFile  = "_headers"
+	CaddyFile           = "Caddyfile"
+)
+
+const (
+	dirMode  = 0o755
+	fileMode = 0o644
+)
+
+// unsafeChars are the characters replaced in file names.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._@-]`)
+
+// Entry is a resource and the path of its JRD file, relative to the export.
+type Entry struct {
+	Subject string
+	Path    string
+}
+
+// Write exports the webfingers to the directory. JRD files of resources
+// that no longer exist are removed.
+func Write(dir string, fingers webfingers.WebFingers) ([]Entry, error) {
+	entries := newEntries(fingers)
+
+	if err := os.MkdirAll(filepath.Join(dir, Dir), dirMode); err != nil {
+		return nil, fmt.Errorf("error creating export directory: %w", err)
+	}
+
+	// Write the JRDs
+	for _, e := range entries {
+		body, err := encode(fingers[e.Subject])
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeFile(dir, e.Path, body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := removeStale(dir, entries); err != nil {
+		return nil, err
+	}
+
+	// Write the rules
+	files := map[string][]byte{
+		NginxFile:                      nginxConfig(entries),
+		ApacheFile:                     apacheConfig(entries),
+		filepath.Join(Dir, ApacheFile): apacheHeaders(),
+		NetlifyRedirectFile:            netlifyRedirects(entries),
+		NetlifyHeadersFile:             netlifyHeaders(),
+		CaddyFile:                      caddyConfig(entries),
+	}
+
+	for name, data := range files {
+		if err := writeFile(dir, name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// newEntries gives every webfinger a unique file name based on its subject,
+// sorted by subject so exports are stable.
+func newEntries(fingers webfingers.WebFingers) []Entry {
+	subjects := make([]string, 0, len(fingers))
+	for subject := range fingers {
+		subjects = append(subjects, subject)
+	}
+
+	sort.Strings(subjects)
+
+	entries := make([]Entry, 0, len(subjects))
+	used := make(map[string]bool, len(subjects))
+
+	for _, subject := range subjects {
+		base := unsafeChars.ReplaceAllString(subject, "_")
+		name := base
+
+		// Subjects that differ only in unsafe characters get a suffix
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = base + "-" + strconv.Itoa(i)
+		}
+
+		used[strings.ToLower(name)] = true
+
+		entries = append(entries, Entry{Subject: subject, Path: Dir + "/" + name + ".json"})
+	}
+
+	return entries
+}
+
+// encode returns the JRD of the webfinger like the server writes it, with
+// the links sorted so exports are stable.
+func encode(finger *webfingers.WebFinger) ([]byte, error) {
+	sorted := *finger
+	sorted.Links = append([]webfingers.Link(nil), finger.Links...)
+
+	sort.SliceStable(sorted.Links, func(i, j int) bool {
+		return sorted.Links[i].Rel < sorted.Links[j].Rel
+	})
+
+	body, err := json.Marshal(&sorted)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding json: %w", err)
+	}
+
+	return append(body, '\n'), nil
+}
+
+// removeStale removes the JRD files that aren't in the entries.
+func removeStale(dir string, entries []Entry) error {
+	keep := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		keep[filepath.FromSlash(e.Path)] = true
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, Dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error listing export: %w", err)
+	}
+
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || keep[rel] {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("error removing stale file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func writeFile(dir, name string, data []byte) error {
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), data, fileMode); err != nil {
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// queryValues returns the ways clients send the resource in the query
+// string, for servers that match it before decoding.
+func queryValues(subject string) []string {
+	escaped := url.QueryEscape(subject)
+	if escaped == subject {
+		return []string{subject}
+	}
+
+	return []string{subject, escaped}
+}
+
+// nginxConfig returns the locations to include in the server block that
+// serves the export.
+func nginxConfig(entries []Entry) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("# Webfinger rules generated by finger export.\n")
+	b.WriteString("# Include them in the server block whose root is the export.\n")
+	b.WriteString("location = /.well-known/webfinger {\n")
+	b.WriteString("    add_header Access-Control-Allow-Origin \"*\" always;\n\n")
+	b.WriteString("    if ($arg_resource = \"\") {\n        return 400 \"No resource provided\\n\";\n    }\n")
+
+	for _, e := range entries {
+		for _, v := range queryValues(e.Subject) {
+			fmt.Fprintf(&b, "    if ($arg_resource = %s) {\n        rewrite ^ /%s? last;\n    }\n", quote(v), e.Path)
+		}
+	}
+
+	b.WriteString("\n    return 404 \"Resource not found\\n\";\n}\n\n")
+	fmt.Fprintf(&b, "location ^~ /%s/ {\n", Dir)
+	b.WriteString("    types { }\n")
+	b.WriteString("    default_type application/jrd+json;\n")
+	b.WriteString("    add_header Access-Control-Allow-Origin \"*\" always;\n")
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
+
+// apacheConfig returns the .htaccess at the root of the export.
+func apacheConfig(entries []Entry) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("# Webfinger rules generated by finger export. Requires mod_rewrite.\n")
+	b.WriteString("RewriteEngine On\n\n")
+	b.WriteString("RewriteCond %{QUERY_STRING} !(^|&)resource=[^&]\n")
+	b.WriteString("RewriteRule ^\\.well-known/webfinger$ - [R=400,L]\n")
+
+	for _, e := range entries {
+		values := queryValues(e.Subject)
+		for i, v := range values {
+			values[i] = regexp.QuoteMeta(v)
+		}
+
+		fmt.Fprintf(&b, "\nRewriteCond %%{QUERY_STRING} \"(^|&)resource=(%s)(&|$)\"\n", strings.Join(values, "|"))
+		fmt.Fprintf(&b, "RewriteRule ^\\.well-known/webfinger$ /%s [L]\n", e.Path)
+	}
+
+	b.WriteString("\nRewriteRule ^\\.well-known/webfinger$ - [R=404,L]\n")
+
+	return b.Bytes()
+}
+
+// apacheHeaders returns the .htaccess of the JRD directory.
+func apacheHeaders() []byte {
+	return []byte(`# Webfinger headers generated by finger export.
+ForceType application/jrd+json
+
+<IfModule mod_headers.c>
+    Header set Access-Control-Allow-Origin "*"
+</IfModule>
+`)
+}
+
+// netlifyRedirects returns the _redirects file of the export. Netlify
+// can't tell missing resources apart, so they get a 404 like unknown ones.
+func netlifyRedirects(entries []Entry) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("# Webfinger rules generated by finger export.\n")
+
+	for _, e := range entries {
+		fmt.Fprintf(&b, "/.well-known/webfinger resource=%s /%s 200\n", e.Subject, e.Path)
+	}
+
+	return b.Bytes()
+}
+
+// netlifyHeaders returns the _headers file of the export.
+func netlifyHeaders() []byte {
+	return []byte(`# Webfinger headers generated by finger export.
+/.well-known/webfinger
+  Content-Type: application/jrd+json
+  Access-Control-Allow-Origin: *
+
+/` + Dir + `/*
+  Content-Type: application/jrd+json
+  Access-Control-Allow-Origin: *
+`)
+}
+
+// caddyConfig returns the directives to include in the site block that
+// serves the export.
+func caddyConfig(entries []Entry) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("# Webfinger rules generated by finger export.\n")
+	b.WriteString("# Include them in the site block whose root is the export.\n")
+	b.WriteString("handle /.well-known/webfinger {\n")
+	b.WriteString("\theader Access-Control-Allow-Origin \"*\"\n\n")
+	b.WriteString("\troute {\n")
+
+	for i, e := range entries {
+		fmt.Fprintf(&b, "\t\t@resource%d query %s\n", i, quote("resource="+e.Subject))
+		fmt.Fprintf(&b, "\t\trewrite @resource%d /%s\n\n", i, e.Path)
+	}
+
+	b.WriteString("\t\t@missing expression `{query.resource} == \"\"`\n")
+	b.WriteString("\t\trespond @missing \"No resource provided\" 400\n\n")
+	fmt.Fprintf(&b, "\t\t@unknown not path /%s/*\n", Dir)
+	b.WriteString("\t\trespond @unknown \"Resource not found\" 404\n\n")
+	b.WriteString("\t\theader Content-Type application/jrd+json\n")
+	b.WriteString("\t\tfile_server\n")
+	b.WriteString("\t}\n}\n")
+
+	return b.Bytes()
+}
+
+// quote quotes s for nginx and Caddy configs.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,198 @@
+package export_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/export"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+func exportFingers() webfingers.WebFingers {
+	return webfingers.WebFingers{
+		"acct:alice@example.com": {
+			Subject:    "acct:alice@example.com",
+			Properties: map[string]string{"name": "Alice"},
+			Links: []webfingers.Link{
+				{Rel: "self", Href: "https://example.com/alice"},
+				{Rel: "avatar", Href: "https://example.com/alice.png"},
+			},
+		},
+		"acct:a.b@example.com":    {Subject: "acct:a.b@example.com"},
+		"acct:a/b@example.com":    {Subject: "acct:a/b@example.com"},
+		"https://example.com/bob": {Subject: "https://example.com/bob"},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	entries, err := export.Write(dir, exportFingers())
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// File names are safe and unique, and entries sorted by subject
+	want := []export.Entry{
+		{Subject: "acct:a.b@example.com", Path: "webfinger/acct_a.b@example.com.json"},
+		{Subject: "acct:a/b@example.com", Path: "webfinger/acct_a_b@example.com.json"},
+		{Subject: "acct:alice@example.com", Path: "webfinger/acct_alice@example.com.json"},
+		{Subject: "https://example.com/bob", Path: "webfinger/https___example.com_bob.json"},
+	}
+
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("Write() = %+v, want %+v", entries, want)
+	}
+
+	// The JRDs have sorted links
+	data, err := os.ReadFile(filepath.Join(dir, "webfinger", "acct_alice@example.com.json"))
+	if err != nil {
+		t.Fatalf("error reading JRD: %v", err)
+	}
+
+	var got webfingers.WebFinger
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("error decoding JRD: %v", err)
+	}
+
+	wantFinger := webfingers.WebFinger{
+		Subject:    "acct:alice@example.com",
+		Properties: map[string]string{"name": "Alice"},
+		Links: []webfingers.Link{
+			{Rel: "avatar", Href: "https://example.com/alice.png"},
+			{Rel: "self", Href: "https://example.com/alice"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, wantFinger) {
+		t.Errorf("JRD = %+v, want %+v", got, wantFinger)
+	}
+}
+
+func TestWrite_Collisions(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:a:b@example.com": {Subject: "acct:a:b@example.com"},
+		"acct:a/b@example.com": {Subject: "acct:a/b@example.com"},
+		"acct:A_b@example.com": {Subject: "acct:A_b@example.com"},
+	}
+
+	entries, err := export.Write(t.TempDir(), fingers)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// Names also differ on case-insensitive file systems
+	want := []string{
+		"webfinger/acct_A_b@example.com.json",
+		"webfinger/acct_a_b@example.com-2.json",
+		"webfinger/acct_a_b@example.com-3.json",
+	}
+
+	for i, e := range entries {
+		if e.Path != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, e.Path, want[i])
+		}
+	}
+}
+
+func TestWrite_RemovesStale(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fingers := exportFingers()
+
+	if _, err := export.Write(dir, fingers); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	delete(fingers, "https://example.com/bob")
+
+	if _, err := export.Write(dir, fingers); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "webfinger", "https___example.com_bob.json")); !os.IsNotExist(err) {
+		t.Errorf("stale JRD was not removed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "webfinger", "acct_alice@example.com.json")); err != nil {
+		t.Errorf("JRD was removed: %v", err)
+	}
+}
+
+func TestWrite_Rules(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if _, err := export.Write(dir, exportFingers()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{export.NginxFile, []string{
+			`if ($arg_resource = "") {`,
+			`if ($arg_resource = "acct:alice@example.com") {`,
+			`if ($arg_resource = "acct%3Aalice%40example.com") {`,
+			`rewrite ^ /webfinger/acct_alice@example.com.json? last;`,
+			`return 404`,
+			`default_type application/jrd+json;`,
+			`add_header Access-Control-Allow-Origin "*" always;`,
+		}},
+		{export.ApacheFile, []string{
+			`RewriteRule ^\.well-known/webfinger$ - [R=400,L]`,
+			`RewriteCond %{QUERY_STRING} "(^|&)resource=(acct:alice@example\.com|acct%3Aalice%40example\.com)(&|$)"`,
+			`RewriteRule ^\.well-known/webfinger$ /webfinger/acct_alice@example.com.json [L]`,
+			`RewriteRule ^\.well-known/webfinger$ - [R=404,L]`,
+		}},
+		{filepath.Join("webfinger", export.ApacheFile), []string{
+			`ForceType application/jrd+json`,
+			`Header set Access-Control-Allow-Origin "*"`,
+		}},
+		{export.NetlifyRedirectFile, []string{
+			`/.well-known/webfinger resource=acct:alice@example.com /webfinger/acct_alice@example.com.json 200`,
+			`/.well-known/webfinger resource=https://example.com/bob /webfinger/https___example.com_bob.json 200`,
+		}},
+		{export.NetlifyHeadersFile, []string{
+			`Content-Type: application/jrd+json`,
+			`Access-Control-Allow-Origin: *`,
+		}},
+		{export.CaddyFile, []string{
+			`@resource2 query "resource=acct:alice@example.com"`,
+			`rewrite @resource2 /webfinger/acct_alice@example.com.json`,
+			`respond @missing "No resource provided" 400`,
+			`respond @unknown "Resource not found" 404`,
+			`header Content-Type application/jrd+json`,
+			`header Access-Control-Allow-Origin "*"`,
+		}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.file, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(filepath.Join(dir, tc.file))
+			if err != nil {
+				t.Fatalf("error reading rules: %v", err)
+			}
+
+			for _, want := range tc.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("%s is missing %q:\n%s", tc.file, want, data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package fingerreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+var (
+	// ErrResourceNotFound is returned when editing a resource that doesn't exist.
+	ErrResourceNotFound = errors.New("resource not found")
+	// ErrResourceExists is returned when adding a resource that already exists.
+	ErrResourceExists = errors.New("resource already exists")
+	// ErrInvalidField is returned when a field can't be added to a resource.
+	ErrInvalidField = errors.New("invalid field")
+)
+
+// FieldName returns the name a field is written with: the alias of a URN
+// that has one, or the field itself.
+func FieldName(urnAliases webfingers.URNAliases, field string) string {
+	if _, ok := urnAliases[field]; ok {
+		return field
+	}
+
+	for alias, urn := range urnAliases {
+		if urn == field {
+			return alias
+		}
+	}
+
+	return field
+}
+
+// ParseFields parses name=value properties and rel=href links into the
+// fields of a resource. Full URNs are replaced by their alias, and link
+// hrefs must be URIs while property values must not be, since they'd be
+// served as links.
+func ParseFields(properties, links []string, urnAliases webfingers.URNAliases) (map[string]string, error) {
+	fields := make(map[string]string, len(properties)+len(links))
+
+	parse := func(field string, link bool) error {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("%w: %q must be name=value", ErrInvalidField, field)
+		}
+
+		_, err := url.ParseRequestURI(value)
+
+		switch {
+		case link && err != nil:
+			return fmt.Errorf("%w: link %s must be a URI", ErrInvalidField, name)
+		case !link && err == nil:
+			return fmt.Errorf("%w: property %s is a URI and would be served as a link", ErrInvalidField, name)
+		}
+
+		fields[FieldName(urnAliases, name)] = value
+
+		return nil
+	}
+
+	for _, p := range properties {
+		if err := parse(p, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, l := range links {
+		if err := parse(l, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return fields, nil
+}
+
+// EditResource changes the fields of a resource in the fingers file with
+// edit, and saves the file keeping its comments if the result is valid. The
+// resource must be new if create is true, or exist otherwise. It returns the
+// key of the resource in the file. Like the admin API, it refuses to save
+// if the files changed while editing.
+func EditResource(
+	ctx context.Context,
+	cfg *config.Config,
+	resource string,
+	create bool,
+	edit func(fields map[string]string, urnAliases webfingers.URNAliases) error,
+) (string, error) {
+	source := NewSource(cfg)
+
+	resources, urnAliases, err := source.Load(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	key, exists := resources.Find(resource)
+
+	switch {
+	case create && exists:
+		return "", fmt.Errorf("%w: %s", ErrResourceExists, resource)
+	case !create && !exists:
+		return "", fmt.Errorf("%w: %s", ErrResourceNotFound, resource)
+	case create:
+		key = resource
+		resources[key] = map[string]string{}
+	}
+
+	if resources[key] == nil {
+		resources[key] = map[string]string{}
+	}
+
+	if err := edit(resources[key], urnAliases); err != nil {
+		return "", err
+	}
+
+	// Use the same rules as the server
+	if _, err := webfingers.NewWebFingers(resources, urnAliases); err != nil {
+		return "", fmt.Errorf("error validating fingers: %w", err)
+	}
+
+	if err := source.Save(ctx, resources, urnAliases); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// RemoveResource removes a resource from the fingers file, keeping its
+// comments. It returns the key the resource had in the file.
+func RemoveResource(ctx context.Context, cfg *config.Config, resource string) (string, error) {
+	source := NewSource(cfg)
+
+	resources, urnAliases, err := source.Load(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := resources.Find(resource)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrResourceNotFound, resource)
+	}
+
+	delete(resources, key)
+
+	if err := source.Save(ctx, resources, urnAliases); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
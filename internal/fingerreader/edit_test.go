@@ -0,0 +1,180 @@
+package fingerreader_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+func TestParseFields(t *testing.T) {
+	t.Parallel()
+
+	urnAliases := webfingers.URNAliases{
+		"name":   "http://schema.org/name",
+		"avatar": "http://webfinger.net/rel/avatar",
+	}
+
+	tests := []struct {
+		name       string
+		properties []string
+		links      []string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "aliases and URIs",
+			properties: []string{"name=Bob", "http://example.com/rel/age=42"},
+			links:      []string{"avatar=https://example.com/bob.png"},
+			want: map[string]string{
+				"name":                       "Bob",
+				"http://example.com/rel/age": "42",
+				"avatar":                     "https://example.com/bob.png",
+			},
+		},
+		{
+			name:       "full URNs use their alias",
+			properties: []string{"http://schema.org/name=Bob"},
+			want:       map[string]string{"name": "Bob"},
+		},
+		{
+			name:       "value with equals",
+			properties: []string{"name=a=b"},
+			want:       map[string]string{"name": "a=b"},
+		},
+		{
+			name:       "missing value",
+			properties: []string{"name"},
+			wantErr:    true,
+		},
+		{
+			name:       "plain names",
+			properties: []string{"nickname=Bobby"},
+			want:       map[string]string{"nickname": "Bobby"},
+		},
+		{
+			name:    "link is not a URI",
+			links:   []string{"avatar=bob.png"},
+			wantErr: true,
+		},
+		{
+			name:       "property is a URI",
+			properties: []string{"name=https://example.com/bob"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := fingerreader.ParseFields(tc.properties, tc.links, urnAliases)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseFields() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseFields() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEditResource(t *testing.T) {
+	t.Parallel()
+
+	ctx := log.WithLogger(context.Background(), log.NewLogger(&strings.Builder{}, config.NewConfig()))
+	dir := t.TempDir()
+
+	cfg := config.NewConfig()
+	cfg.FingerPath = filepath.Join(dir, "fingers.yml")
+	cfg.URNPath = filepath.Join(dir, "urns.yml")
+
+	for path, content := range map[string]string{cfg.FingerPath: fingersDoc, cfg.URNPath: ""} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	set := func(name, value string) func(map[string]string, webfingers.URNAliases) error {
+		return func(fields map[string]string, _ webfingers.URNAliases) error {
+			fields[name] = value
+
+			return nil
+		}
+	}
+
+	// Resources are found by their subject
+	key, err := fingerreader.EditResource(ctx, cfg, "acct:bob@example.com", false, set("name", "Robert"))
+	if err != nil {
+		t.Fatalf("EditResource() error = %v", err)
+	}
+
+	if key != "bob@example.com" {
+		t.Errorf("EditResource() = %q, want bob@example.com", key)
+	}
+
+	if _, err := fingerreader.EditResource(ctx, cfg, "acct:carol@example.com", true, set("name", "Carol")); err != nil {
+		t.Fatalf("EditResource() error = %v", err)
+	}
+
+	if _, err := fingerreader.EditResource(ctx, cfg, "bob@example.com", true, set("name", "Bob")); !errors.Is(err, fingerreader.ErrResourceExists) {
+		t.Errorf("EditResource() error = %v, want %v", err, fingerreader.ErrResourceExists)
+	}
+
+	if _, err := fingerreader.EditResource(ctx, cfg, "dave@example.com", false, set("name", "Dave")); !errors.Is(err, fingerreader.ErrResourceNotFound) {
+		t.Errorf("EditResource() error = %v, want %v", err, fingerreader.ErrResourceNotFound)
+	}
+
+	// Invalid resources aren't written
+	if _, err := fingerreader.EditResource(ctx, cfg, "not a subject", true, set("name", "Nobody")); err == nil {
+		t.Error("EditResource() expected error for an invalid subject, got nil")
+	}
+
+	// Files edited while editing aren't overwritten
+	edited := false
+	changeFile := func(fields map[string]string, _ webfingers.URNAliases) error {
+		edited = true
+
+		return os.WriteFile(cfg.URNPath, []byte("name: http://schema.org/name\n"), 0o600)
+	}
+
+	if _, err := fingerreader.EditResource(ctx, cfg, "bob@example.com", false, changeFile); !edited || !errors.Is(err, fingerreader.ErrChanged) {
+		t.Errorf("EditResource() error = %v, want %v", err, fingerreader.ErrChanged)
+	}
+
+	if err := os.WriteFile(cfg.URNPath, nil, 0o600); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	if _, err := fingerreader.RemoveResource(ctx, cfg, "alice@example.com"); err != nil {
+		t.Fatalf("RemoveResource() error = %v", err)
+	}
+
+	if _, err := fingerreader.RemoveResource(ctx, cfg, "alice@example.com"); !errors.Is(err, fingerreader.ErrResourceNotFound) {
+		t.Errorf("RemoveResource() error = %v, want %v", err, fingerreader.ErrResourceNotFound)
+	}
+
+	got, _ := os.ReadFile(cfg.FingerPath)
+	want := `# Our team
+
+bob@example.com:
+  name: Robert
+
+acct:carol@example.com:
+  name: Carol
+`
+
+	if string(got) != want {
+		t.Errorf("fingers file got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -53,6 +53,23 @@ func (f *FingerReader) ReadFiles(cfg *config.Config) error {
 }
 
 func (f *FingerReader) ReadFingerFile(ctx context.Context) (webfingers.WebFingers, error) {
+	resources, urnAliases, err := f.ReadSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse raw data
+	fingers, err := webfingers.NewWebFingers(resources, urnAliases)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing raw fingers: %w", err)
+	}
+
+	return fingers, nil
+}
+
+// ReadSource parses the resources and URN aliases of the files without
+// turning them into webfingers.
+func (f *FingerReader) ReadSource(ctx context.Context) (webfingers.Resources, webfingers.URNAliases, error) {
 	l := log.FromContext(ctx)
 
 	urnAliases := make(webfingers.URNAliases)
@@ -60,30 +77,73 @@ func (f *FingerReader) ReadFingerFile(ctx context.Context) (webfingers.WebFinger
 
 	// Parse the URNs file
 	if err := yaml.Unmarshal(f.URNSFile, &urnAliases); err != nil {
-		return nil, fmt.Errorf("error unmarshalling URNs file: %w", err)
+		return nil, nil, fmt.Errorf("error unmarshalling URNs file: %w", err)
 	}
 
 	// The URNs file must be a map of strings to valid URLs
-	for _, v := range urnAliases {
-		if _, err := url.ParseRequestURI(v); err != nil {
-			return nil, fmt.Errorf("error parsing URN URIs: %w", err)
-		}
+	if err := ValidateURNs(urnAliases); err != nil {
+		return nil, nil, err
 	}
 
 	l.Debug("URNs file parsed successfully", slog.Int("number", len(urnAliases)), slog.Any("data", urnAliases))
 
 	// Parse the fingers file
 	if err := yaml.Unmarshal(f.FingersFile, &resources); err != nil {
-		return nil, fmt.Errorf("error unmarshalling fingers file: %w", err)
+		return nil, nil, fmt.Errorf("error unmarshalling fingers file: %w", err)
 	}
 
 	l.Debug("Fingers file parsed successfully", slog.Int("number", len(resources)), slog.Any("data", resources))
 
-	// Parse raw data
-	fingers, err := webfingers.NewWebFingers(resources, urnAliases)
+	return resources, urnAliases, nil
+}
+
+// ValidateURNs checks that every URN alias points to a valid URI.
+func ValidateURNs(urnAliases webfingers.URNAliases) error {
+	for _, v := range urnAliases {
+		if _, err := url.ParseRequestURI(v); err != nil {
+			return fmt.Errorf("error parsing URN URIs: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Load reads and parses the URNs and fingers files in the config.
+func Load(ctx context.Context, cfg *config.Config) (webfingers.WebFingers, error) {
+	r := NewFingerReader()
+
+	if err := r.ReadFiles(cfg); err != nil {
+		return nil, fmt.Errorf("error reading finger files: %w", err)
+	}
+
+	fingers, err := r.ReadFingerFile(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing raw fingers: %w", err)
+		return nil, fmt.Errorf("error parsing finger files: %w", err)
 	}
 
 	return fingers, nil
 }
+
+// LoadSource reads and parses the URNs and fingers files in the config
+// without turning them into webfingers.
+func LoadSource(ctx context.Context, cfg *config.Config) (webfingers.Resources, webfingers.URNAliases, error) {
+	_, resources, urnAliases, err := readSource(ctx, cfg)
+
+	return resources, urnAliases, err
+}
+
+// readSource is LoadSource, also returning the reader with the files.
+func readSource(ctx context.Context, cfg *config.Config) (*FingerReader, webfingers.Resources, webfingers.URNAliases, error) {
+	r := NewFingerReader()
+
+	if err := r.ReadFiles(cfg); err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading finger files: %w", err)
+	}
+
+	resources, urnAliases, err := r.ReadSource(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error parsing finger files: %w", err)
+	}
+
+	return r, resources, urnAliases, nil
+}
@@ -240,3 +240,107 @@ func TestReadFingerFile(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	l := log.NewLogger(&strings.Builder{}, cfg)
+
+	ctx = log.WithLogger(ctx, l)
+
+	t.Run("loads files", func(t *testing.T) {
+		t.Parallel()
+
+		urnsFileName, urnsCleanup := newTempFile(t, "name: https://schema/name")
+		defer urnsCleanup()
+
+		fingersFileName, fingersCleanup := newTempFile(t, "user@example.com:\n  name: John Doe")
+		defer fingersCleanup()
+
+		cfg := config.NewConfig()
+		cfg.URNPath = urnsFileName
+		cfg.FingerPath = fingersFileName
+
+		got, err := fingerreader.Load(ctx, cfg)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		want := webfingers.WebFingers{
+			"acct:user@example.com": {
+				Subject: "acct:user@example.com",
+				Properties: map[string]string{
+					"https://schema/name": "John Doe",
+				},
+			},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Load() got = %v, want: %v", got, want)
+		}
+	})
+
+	t.Run("errors on missing files", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.NewConfig()
+		cfg.FingerPath = "invalid"
+
+		if _, err := fingerreader.Load(ctx, cfg); err == nil {
+			t.Error("Load() expected error, got nil")
+		}
+	})
+
+	t.Run("errors on invalid files", func(t *testing.T) {
+		t.Parallel()
+
+		fingersFileName, fingersCleanup := newTempFile(t, "invalid:\n  name: John Doe")
+		defer fingersCleanup()
+
+		cfg := config.NewConfig()
+		cfg.URNPath = "urns.yml"
+		cfg.FingerPath = fingersFileName
+
+		if _, err := fingerreader.Load(ctx, cfg); err == nil {
+			t.Error("Load() expected error, got nil")
+		}
+	})
+}
+
+func TestLoadSource(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	l := log.NewLogger(&strings.Builder{}, cfg)
+
+	ctx = log.WithLogger(ctx, l)
+
+	urnsFileName, urnsCleanup := newTempFile(t, "name: https://schema/name")
+	defer urnsCleanup()
+
+	fingersFileName, fingersCleanup := newTempFile(t, "user@example.com:\n  name: John Doe")
+	defer fingersCleanup()
+
+	cfg.URNPath = urnsFileName
+	cfg.FingerPath = fingersFileName
+
+	resources, urnAliases, err := fingerreader.LoadSource(ctx, cfg)
+	if err != nil {
+		t.Fatalf("LoadSource() error = %v", err)
+	}
+
+	wantResources := webfingers.Resources{
+		"user@example.com": {"name": "John Doe"},
+	}
+	if !reflect.DeepEqual(resources, wantResources) {
+		t.Errorf("LoadSource() resources = %v, want: %v", resources, wantResources)
+	}
+
+	wantURNs := webfingers.URNAliases{"name": "https://schema/name"}
+	if !reflect.DeepEqual(urnAliases, wantURNs) {
+		t.Errorf("LoadSource() URN aliases = %v, want: %v", urnAliases, wantURNs)
+	}
+}
@@ -0,0 +1,612 @@
+package fingerreader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/webfingers"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultIndent is the indentation of documents that have none yet.
+	defaultIndent = 2
+	// defaultFileMode is the file mode of new files.
+	defaultFileMode = 0o644
+	// strTag is the YAML tag of strings.
+	strTag = "!!str"
+)
+
+var (
+	// ErrInvalidDocument is returned when a file is not a map of entries.
+	ErrInvalidDocument = errors.New("invalid document")
+	// ErrChanged is returned when saving over a file that changed since it
+	// was loaded.
+	ErrChanged = errors.New("changed since it was loaded")
+)
+
+// Document is a fingers or URNs file edited through its YAML nodes, so
+// comments, key order and formatting are kept.
+type Document struct {
+	root   *yaml.Node
+	indent int
+
+	// spaced are the entries preceded by a blank line, which YAML nodes
+	// don't keep.
+	spaced map[*yaml.Node]bool
+	// spaceNew is whether new entries get a blank line before them.
+	spaceNew bool
+}
+
+// ParseDocument parses a fingers or URNs file.
+func ParseDocument(data []byte) (*Document, error) {
+	d := &Document{
+		indent: detectIndent(data),
+		spaced: map[*yaml.Node]bool{},
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+	}
+
+	// Empty files have no entries yet
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	d.root = &doc
+
+	m := d.mapping()
+	if m.Kind != yaml.MappingNode {
+		// A file with only comments is an empty map
+		if m.Kind == yaml.ScalarNode && m.Tag == "!!null" {
+			*m = yaml.Node{Kind: yaml.MappingNode, HeadComment: m.HeadComment, FootComment: m.FootComment}
+		} else {
+			return nil, fmt.Errorf("%w: expected a map", ErrInvalidDocument)
+		}
+	}
+
+	// Remember the blank lines between entries
+	lines := strings.Split(string(data), "\n")
+
+	for i := 2; i < len(m.Content); i += 2 {
+		key := m.Content[i]
+
+		before := key.Line - commentLines(key.HeadComment) - 1
+		if before >= 1 && before <= len(lines) && strings.TrimSpace(lines[before-1]) == "" {
+			d.spaced[key] = true
+			d.spaceNew = true
+		}
+	}
+
+	return d, nil
+}
+
+// ReadDocument reads the file at path. Missing files are empty documents.
+func ReadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	d, err := ParseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	return d, nil
+}
+
+func (d *Document) mapping() *yaml.Node {
+	return d.root.Content[0]
+}
+
+// find returns the index of the key node of the entry, or -1.
+func (d *Document) find(key string) int {
+	m := d.mapping()
+
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Keys returns the keys of the entries in the order of the file.
+func (d *Document) Keys() []string {
+	m := d.mapping()
+	keys := make([]string, 0, len(m.Content)/2)
+
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		keys = append(keys, m.Content[i].Value)
+	}
+
+	return keys
+}
+
+// set replaces the value of the entry, or adds it at the end.
+func (d *Document) set(key string, value *yaml.Node) {
+	m := d.mapping()
+
+	if i := d.find(key); i >= 0 {
+		m.Content[i+1] = value
+
+		return
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: strTag, Value: key}
+	if d.spaceNew {
+		d.spaced[keyNode] = true
+	}
+
+	m.Content = append(m.Content, keyNode, value)
+}
+
+// Delete removes the entry, and reports whether it existed.
+func (d *Document) Delete(key string) bool {
+	i := d.find(key)
+	if i < 0 {
+		return false
+	}
+
+	m := d.mapping()
+	m.Content = append(m.Content[:i], m.Content[i+2:]...)
+
+	return true
+}
+
+// SetResource sets the fields of the resource. Fields that already exist
+// keep their place and comments, removed ones are deleted and new ones are
+// added in alphabetical order.
+func (d *Document) SetResource(key string, fields map[string]string) {
+	// Resources left empty in the file stay that way
+	if i := d.find(key); i >= 0 && len(fields) == 0 {
+		if v := d.mapping().Content[i+1]; v.Kind == yaml.ScalarNode && v.Tag == "!!null" {
+			return
+		}
+	}
+
+	var value *yaml.Node
+
+	if i := d.find(key); i >= 0 && d.mapping().Content[i+1].Kind == yaml.MappingNode {
+		value = d.mapping().Content[i+1]
+	} else {
+		value = &yaml.Node{Kind: yaml.MappingNode}
+	}
+
+	// Update and remove the existing fields
+	seen := map[string]bool{}
+	content := value.Content[:0]
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		field := value.Content[i].Value
+
+		v, ok := fields[field]
+		if !ok {
+			continue
+		}
+
+		seen[field] = true
+
+		setScalar(value.Content[i+1], v)
+		content = append(content, value.Content[i], value.Content[i+1])
+	}
+
+	// Add the new ones
+	added := make([]string, 0, len(fields))
+
+	for field := range fields {
+		if !seen[field] {
+			added = append(added, field)
+		}
+	}
+
+	sort.Strings(added)
+
+	for _, field := range added {
+		content = append(content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: strTag, Value: field},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: strTag, Value: fields[field]},
+		)
+	}
+
+	value.Content = content
+
+	d.set(key, value)
+}
+
+// SetURN sets the URN of the alias.
+func (d *Document) SetURN(name, urn string) {
+	if i := d.find(name); i >= 0 && d.mapping().Content[i+1].Kind == yaml.ScalarNode {
+		value := d.mapping().Content[i+1]
+		setScalar(value, urn)
+
+		return
+	}
+
+	d.set(name, &yaml.Node{Kind: yaml.ScalarNode, Tag: strTag, Value: urn})
+}
+
+// SetResources makes the document have exactly the resources, and reports
+// whether anything changed.
+func (d *Document) SetResources(resources webfingers.Resources) bool {
+	before, _ := d.Bytes()
+
+	for _, key := range d.Keys() {
+		if _, ok := resources[key]; !ok {
+			d.Delete(key)
+		}
+	}
+
+	for _, key := range sortedKeys(resources) {
+		d.SetResource(key, resources[key])
+	}
+
+	after, _ := d.Bytes()
+
+	return !bytes.Equal(before, after)
+}
+
+// SetURNs makes the document have exactly the URN aliases, and reports
+// whether anything changed.
+func (d *Document) SetURNs(urnAliases webfingers.URNAliases) bool {
+	before, _ := d.Bytes()
+
+	for _, key := range d.Keys() {
+		if _, ok := urnAliases[key]; !ok {
+			d.Delete(key)
+		}
+	}
+
+	for _, key := range sortedKeys(urnAliases) {
+		d.SetURN(key, urnAliases[key])
+	}
+
+	after, _ := d.Bytes()
+
+	return !bytes.Equal(before, after)
+}
+
+// Bytes encodes the document.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(d.indent)
+
+	if err := enc.Encode(d.root); err != nil {
+		return nil, fmt.Errorf("error encoding document: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding document: %w", err)
+	}
+
+	// Empty maps are encoded as {}, but read as empty files
+	if len(d.mapping().Content) == 0 && d.mapping().HeadComment == "" && d.mapping().FootComment == "" {
+		return []byte{}, nil
+	}
+
+	return d.addBlankLines(buf.Bytes()), nil
+}
+
+// addBlankLines adds back the blank lines before spaced entries.
+func (d *Document) addBlankLines(data []byte) []byte {
+	var out yaml.Node
+	if err := yaml.Unmarshal(data, &out); err != nil || len(out.Content) == 0 {
+		return data
+	}
+
+	encoded := out.Content[0]
+	m := d.mapping()
+
+	if encoded.Kind != yaml.MappingNode || len(encoded.Content) != len(m.Content) {
+		return data
+	}
+
+	lines := strings.Split(string(data), "\n")
+	blank := map[int]bool{}
+
+	for i := 2; i < len(m.Content); i += 2 {
+		if !d.spaced[m.Content[i]] {
+			continue
+		}
+
+		key := encoded.Content[i]
+		before := key.Line - commentLines(key.HeadComment) - 1
+
+		if before >= 1 && strings.TrimSpace(lines[before-1]) != "" {
+			blank[before] = true
+		}
+	}
+
+	var buf strings.Builder
+
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+
+		buf.WriteString(line)
+
+		if blank[i+1] {
+			buf.WriteByte('\n')
+		}
+	}
+
+	return []byte(buf.String())
+}
+
+// WriteFile writes the document to path atomically.
+func (d *Document) WriteFile(path string) error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(path, data)
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames
+// it over path, so readers never see a partially written file. The mode of
+// the existing file is kept.
+func WriteFileAtomic(path string, data []byte) error {
+	// Replace the target of symlinks instead of the link
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	mode := os.FileMode(defaultFileMode)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+
+	// Clean up if anything fails
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("error writing temporary file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("error syncing temporary file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temporary file: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("error setting file mode: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Source loads the resources and URN aliases from the files in the config
+// and saves changes back to them. It refuses to save over files that
+// changed since they were last loaded or saved.
+type Source struct {
+	cfg *config.Config
+
+	mu sync.Mutex
+	// sums are the hashes of the files as they were loaded or saved.
+	sums map[string][sha256.Size]byte
+}
+
+// NewSource creates a source for the files in the config.
+func NewSource(cfg *config.Config) *Source {
+	return &Source{cfg: cfg, sums: map[string][sha256.Size]byte{}}
+}
+
+// Load reads and parses the files like LoadSource, and remembers them.
+func (s *Source) Load(ctx context.Context) (webfingers.Resources, webfingers.URNAliases, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, resources, urnAliases, err := readSource(ctx, s.cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.sums[s.cfg.URNPath] = sha256.Sum256(r.URNSFile)
+	s.sums[s.cfg.FingerPath] = sha256.Sum256(r.FingersFile)
+
+	return resources, urnAliases, nil
+}
+
+// Save writes the resources and URN aliases to the files, editing them in
+// place. Files are only written if they change, and the URNs file is
+// restored if the fingers file can't be written.
+func (s *Source) Save(_ context.Context, resources webfingers.Resources, urnAliases webfingers.URNAliases) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urnsData, urnsExists, err := s.readUnchanged(s.cfg.URNPath)
+	if err != nil {
+		return err
+	}
+
+	fingersData, _, err := s.readUnchanged(s.cfg.FingerPath)
+	if err != nil {
+		return err
+	}
+
+	urns, err := ParseDocument(urnsData)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", s.cfg.URNPath, err)
+	}
+
+	fingers, err := ParseDocument(fingersData)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", s.cfg.FingerPath, err)
+	}
+
+	// Write the aliases first so the new fingers can use them
+	newURNs := urnsData
+	if urns.SetURNs(urnAliases) {
+		if newURNs, err = s.write(s.cfg.URNPath, urns); err != nil {
+			return err
+		}
+	}
+
+	if fingers.SetResources(resources) {
+		if _, err := s.write(s.cfg.FingerPath, fingers); err != nil {
+			// Don't leave the aliases out of sync with the fingers
+			if !bytes.Equal(newURNs, urnsData) {
+				if rErr := s.restore(s.cfg.URNPath, urnsData, urnsExists); rErr != nil {
+					return errors.Join(err, rErr)
+				}
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readUnchanged reads the file at path, and whether it exists, if it's
+// the same as when it was loaded or saved. Missing files are empty.
+func (s *Source) readUnchanged(path string) ([]byte, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, false, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	if sum, ok := s.sums[path]; !ok || sum != sha256.Sum256(data) {
+		return nil, false, fmt.Errorf("%s %w", path, ErrChanged)
+	}
+
+	return data, err == nil, nil
+}
+
+// write writes the document to path and remembers it.
+func (s *Source) write(path string, d *Document) ([]byte, error) {
+	data, err := d.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := WriteFileAtomic(path, data); err != nil {
+		return nil, err
+	}
+
+	s.sums[path] = sha256.Sum256(data)
+
+	return data, nil
+}
+
+// restore puts back the file at path as it was before being written.
+func (s *Source) restore(path string, data []byte, exists bool) error {
+	var err error
+	if exists {
+		err = WriteFileAtomic(path, data)
+	} else {
+		err = os.Remove(path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error restoring %s: %w", path, err)
+	}
+
+	s.sums[path] = sha256.Sum256(data)
+
+	return nil
+}
+
+// setScalar changes the value of a scalar node, keeping its style and
+// its tag if the value still has the same type.
+func setScalar(node *yaml.Node, value string) {
+	if node.Kind == yaml.ScalarNode && node.Value == value {
+		return
+	}
+
+	tag := strTag
+	if node.Kind == yaml.ScalarNode && node.Tag == resolveTag(value) {
+		tag = node.Tag
+	}
+
+	*node = yaml.Node{
+		Kind:        yaml.ScalarNode,
+		Style:       node.Style,
+		Tag:         tag,
+		Value:       value,
+		HeadComment: node.HeadComment,
+		LineComment: node.LineComment,
+		FootComment: node.FootComment,
+	}
+}
+
+// resolveTag returns the tag a plain value would be read as.
+func resolveTag(value string) string {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil || len(doc.Content) == 0 {
+		return strTag
+	}
+
+	if n := doc.Content[0]; n.Kind == yaml.ScalarNode && n.Value == value {
+		return n.Tag
+	}
+
+	return strTag
+}
+
+// detectIndent returns the indentation of the first indented line.
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed == line || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		return len(line) - len(trimmed)
+	}
+
+	return defaultIndent
+}
+
+// commentLines returns the number of lines of a comment.
+func commentLines(comment string) int {
+	if comment == "" {
+		return 0
+	}
+
+	return strings.Count(comment, "\n") + 1
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
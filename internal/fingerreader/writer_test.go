@@ -0,0 +1,362 @@
+package fingerreader_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+const fingersDoc = `# Our team
+
+# Alice runs the blog
+alice@example.com:
+  name: Alice # Full name
+  age: 30
+  avatar: "https://example.com/alice.png"
+
+bob@example.com:
+  name: Bob
+`
+
+func TestDocument(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		doc  string
+		edit func(d *fingerreader.Document)
+		want string
+	}{
+		{
+			name: "no changes",
+			doc:  fingersDoc,
+			edit: func(d *fingerreader.Document) {},
+			want: fingersDoc,
+		},
+		{
+			name: "update fields",
+			doc:  fingersDoc,
+			edit: func(d *fingerreader.Document) {
+				d.SetResource("alice@example.com", map[string]string{
+					"name":    "Alice Liddell",
+					"age":     "31",
+					"avatar":  "https://example.com/alice.jpg",
+					"website": "https://alice.example.com",
+				})
+			},
+			want: `# Our team
+
+# Alice runs the blog
+alice@example.com:
+  name: Alice Liddell # Full name
+  age: 31
+  avatar: "https://example.com/alice.jpg"
+  website: https://alice.example.com
+
+bob@example.com:
+  name: Bob
+`,
+		},
+		{
+			name: "remove fields",
+			doc:  fingersDoc,
+			edit: func(d *fingerreader.Document) {
+				d.SetResource("alice@example.com", map[string]string{"name": "Alice"})
+			},
+			want: `# Our team
+
+# Alice runs the blog
+alice@example.com:
+  name: Alice # Full name
+
+bob@example.com:
+  name: Bob
+`,
+		},
+		{
+			name: "add resource",
+			doc:  fingersDoc,
+			edit: func(d *fingerreader.Document) {
+				d.SetResource("carol@example.com", map[string]string{"name": "Carol", "id": "42"})
+			},
+			want: fingersDoc + `
+carol@example.com:
+  id: "42"
+  name: Carol
+`,
+		},
+		{
+			name: "delete resource",
+			doc:  fingersDoc,
+			edit: func(d *fingerreader.Document) {
+				d.Delete("alice@example.com")
+			},
+			want: `# Our team
+
+bob@example.com:
+  name: Bob
+`,
+		},
+		{
+			name: "keeps empty resources",
+			doc:  "alice@example.com:\nbob@example.com:\n  name: Bob\n",
+			edit: func(d *fingerreader.Document) {
+				d.SetResources(webfingers.Resources{"alice@example.com": nil, "bob@example.com": {"name": "Robert"}})
+			},
+			want: "alice@example.com:\nbob@example.com:\n  name: Robert\n",
+		},
+		{
+			name: "keeps indentation",
+			doc:  "alice@example.com:\n    name: Alice\n",
+			edit: func(d *fingerreader.Document) {
+				d.SetResource("bob@example.com", map[string]string{"name": "Bob"})
+			},
+			want: "alice@example.com:\n    name: Alice\nbob@example.com:\n    name: Bob\n",
+		},
+		{
+			name: "empty file",
+			doc:  "",
+			edit: func(d *fingerreader.Document) {
+				d.SetResource("alice@example.com", map[string]string{"name": "Alice"})
+			},
+			want: "alice@example.com:\n  name: Alice\n",
+		},
+		{
+			name: "URN aliases",
+			doc:  "# Aliases\nname: http://schema.org/name # Names\n",
+			edit: func(d *fingerreader.Document) {
+				d.SetURN("name", "https://schema.org/name")
+				d.SetURN("avatar", "http://webfinger.net/rel/avatar")
+			},
+			want: "# Aliases\nname: https://schema.org/name # Names\navatar: http://webfinger.net/rel/avatar\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d, err := fingerreader.ParseDocument([]byte(tc.doc))
+			if err != nil {
+				t.Fatalf("ParseDocument() error = %v", err)
+			}
+
+			tc.edit(d)
+
+			got, err := d.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if string(got) != tc.want {
+				t.Errorf("Bytes() got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDocument_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, doc := range []string{"- a list", "key: [unclosed"} {
+		if _, err := fingerreader.ParseDocument([]byte(doc)); err == nil {
+			t.Errorf("ParseDocument(%q) expected error, got nil", doc)
+		}
+	}
+}
+
+func TestDocument_SetResources(t *testing.T) {
+	t.Parallel()
+
+	d, err := fingerreader.ParseDocument([]byte(fingersDoc))
+	if err != nil {
+		t.Fatalf("ParseDocument() error = %v", err)
+	}
+
+	resources := webfingers.Resources{
+		"alice@example.com": {"name": "Alice", "age": "30", "avatar": "https://example.com/alice.png"},
+		"bob@example.com":   {"name": "Bob"},
+	}
+
+	if d.SetResources(resources) {
+		t.Error("SetResources() reported changes for the same resources")
+	}
+
+	delete(resources, "bob@example.com")
+
+	if !d.SetResources(resources) {
+		t.Error("SetResources() reported no changes for a deleted resource")
+	}
+
+	if got := d.Keys(); !reflect.DeepEqual(got, []string{"alice@example.com"}) {
+		t.Errorf("Keys() = %v, want [alice@example.com]", got)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fingers.yml")
+
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	if err := fingerreader.WriteFileAtomic(path, []byte("new")); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	if string(got) != "new" {
+		t.Errorf("expected new content, got %q", got)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the file mode to be kept, got %v", info.Mode().Perm())
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the file, got %d entries", len(entries))
+	}
+}
+
+// newSourceFiles writes the fingers and URNs files and returns their config.
+func newSourceFiles(t *testing.T, fingersName string) *config.Config {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	cfg := config.NewConfig()
+	cfg.FingerPath = filepath.Join(dir, fingersName)
+	cfg.URNPath = filepath.Join(dir, "urns.yml")
+
+	for path, content := range map[string]string{cfg.FingerPath: fingersDoc, cfg.URNPath: ""} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	return cfg
+}
+
+func TestSource_Save(t *testing.T) {
+	t.Parallel()
+
+	ctx := log.WithLogger(context.Background(), log.NewLogger(&strings.Builder{}, config.NewConfig()))
+	cfg := newSourceFiles(t, "fingers.yml")
+	source := fingerreader.NewSource(cfg)
+
+	resources, urnAliases, err := source.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	resources["bob@example.com"]["name"] = "Robert"
+	urnAliases["name"] = "http://schema.org/name"
+
+	if err := source.Save(ctx, resources, urnAliases); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	gotResources, gotURNs, err := fingerreader.LoadSource(ctx, cfg)
+	if err != nil {
+		t.Fatalf("LoadSource() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(gotResources, resources) || !reflect.DeepEqual(gotURNs, urnAliases) {
+		t.Errorf("LoadSource() = %v, %v, want %v, %v", gotResources, gotURNs, resources, urnAliases)
+	}
+
+	// Comments are kept
+	if b, _ := os.ReadFile(cfg.FingerPath); !strings.Contains(string(b), "# Alice runs the blog") {
+		t.Errorf("expected the comments to be kept, got:\n%s", b)
+	}
+
+	// Saving again builds on the saved files
+	resources["bob@example.com"]["name"] = "Bobby"
+
+	if err := source.Save(ctx, resources, urnAliases); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+}
+
+func TestSource_Save_Changed(t *testing.T) {
+	t.Parallel()
+
+	ctx := log.WithLogger(context.Background(), log.NewLogger(&strings.Builder{}, config.NewConfig()))
+	cfg := newSourceFiles(t, "fingers.yml")
+	source := fingerreader.NewSource(cfg)
+
+	resources, urnAliases, err := source.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// Someone edits the file after it was loaded
+	edited := fingersDoc + "\ncarol@example.com:\n  name: Carol\n"
+	if err := os.WriteFile(cfg.FingerPath, []byte(edited), 0o600); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	resources["bob@example.com"]["name"] = "Robert"
+
+	if err := source.Save(ctx, resources, urnAliases); !errors.Is(err, fingerreader.ErrChanged) {
+		t.Fatalf("Save() error = %v, want %v", err, fingerreader.ErrChanged)
+	}
+
+	if b, _ := os.ReadFile(cfg.FingerPath); string(b) != edited {
+		t.Errorf("expected the edit to be kept, got:\n%s", b)
+	}
+
+	// Saving works again once the edit is loaded
+	if resources, urnAliases, err = source.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	resources["bob@example.com"]["name"] = "Robert"
+
+	if err := source.Save(ctx, resources, urnAliases); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+}
+
+func TestSource_Save_Restore(t *testing.T) {
+	t.Parallel()
+
+	ctx := log.WithLogger(context.Background(), log.NewLogger(&strings.Builder{}, config.NewConfig()))
+
+	// The temporary file of a name this long can't be created
+	cfg := newSourceFiles(t, strings.Repeat("f", 250)+".yml")
+	source := fingerreader.NewSource(cfg)
+
+	resources, urnAliases, err := source.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	resources["bob@example.com"]["name"] = "Robert"
+	urnAliases["name"] = "http://schema.org/name"
+
+	if err := source.Save(ctx, resources, urnAliases); err == nil {
+		t.Fatal("Save() error = nil, want an error")
+	}
+
+	// The aliases are back to how they were
+	if b, _ := os.ReadFile(cfg.URNPath); len(b) != 0 {
+		t.Errorf("expected the URNs file to be restored, got:\n%s", b)
+	}
+}
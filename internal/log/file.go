@@ -0,0 +1,163 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// logFileMode is the file mode of new log files.
+const logFileMode = 0o640
+
+// RotatingFile is a log file that is rotated when it grows past a maximum
+// size. Rotated files get a numeric suffix, with ".1" being the newest.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotatingFile opens the log file at path for appending. It's rotated
+// once it reaches maxSize bytes, keeping maxBackups old files. A maxSize of
+// 0 disables rotation.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	file, size, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	f.file = file
+	f.size = size
+
+	return f, nil
+}
+
+// open opens the log file at the path and returns it with its size.
+func (f *RotatingFile) open() (*os.File, int64, error) {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, logFileMode)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error opening log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return nil, 0, fmt.Errorf("error reading log file: %w", err)
+	}
+
+	return file, info.Size(), nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Keep writing to the current file if the rotation fails
+	var rotateErr error
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		rotateErr = f.rotate()
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	if err != nil {
+		return n, fmt.Errorf("error writing log file: %w", err)
+	}
+
+	return n, rotateErr
+}
+
+// rotate shifts the old files by one, moves the current one to ".1" and
+// opens a new one. The log file is always opened again, so logging goes on
+// even if the rotation fails.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("error closing log file: %w", err)
+	}
+
+	rotateErr := f.shiftBackups()
+
+	file, size, err := f.open()
+	if err != nil {
+		return err
+	}
+
+	f.file = file
+	f.size = size
+
+	if rotateErr != nil {
+		return fmt.Errorf("error rotating log file: %w", rotateErr)
+	}
+
+	return nil
+}
+
+func (f *RotatingFile) shiftBackups() error {
+	// Without backups, the current file is simply truncated
+	if f.maxBackups == 0 {
+		return os.Remove(f.path) //nolint:wrapcheck // Wrapped by rotate
+	}
+
+	for i := f.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(f.backupPath(i), f.backupPath(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err //nolint:wrapcheck // Wrapped by rotate
+		}
+	}
+
+	return os.Rename(f.path, f.backupPath(1)) //nolint:wrapcheck // Wrapped by rotate
+}
+
+func (f *RotatingFile) backupPath(i int) string {
+	return f.path + "." + strconv.Itoa(i)
+}
+
+// Reopen opens the log file again, so it can be moved away by tools like
+// logrotate. If the new file can't be opened, logs keep going to the
+// current one.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, size, err := f.open()
+	if err != nil {
+		return err
+	}
+
+	old := f.file
+	f.file = file
+	f.size = size
+
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("error closing log file: %w", err)
+	}
+
+	return nil
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("error closing log file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package log_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/log"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", path, err)
+	}
+
+	return string(b)
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "finger.log")
+
+	f, err := log.OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("error opening file: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("error writing: %v", err)
+		}
+	}
+
+	// Each line overflows the file, and only two backups are kept
+	want := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+
+	for p, content := range want {
+		if got := readFile(t, p); got != content {
+			t.Errorf("expected %q in %s, got %q", content, p, got)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected no third backup, got %v", err)
+	}
+}
+
+func TestRotatingFile_Reopen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "finger.log")
+
+	f, err := log.OpenRotatingFile(path, 0, 0)
+	if err != nil {
+		t.Fatalf("error opening file: %v", err)
+	}
+	defer f.Close()
+
+	_, _ = f.Write([]byte("before\n"))
+
+	// Move the file away like logrotate does
+	moved := filepath.Join(dir, "finger.log.old")
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatalf("error moving file: %v", err)
+	}
+
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("error reopening file: %v", err)
+	}
+
+	_, _ = f.Write([]byte("after\n"))
+
+	if got := readFile(t, moved); got != "before\n" {
+		t.Errorf("expected %q in the moved file, got %q", "before\n", got)
+	}
+
+	if got := readFile(t, path); got != "after\n" {
+		t.Errorf("expected %q in the new file, got %q", "after\n", got)
+	}
+}
+
+func TestRotatingFile_ReopenFails(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "finger.log")
+
+	f, err := log.OpenRotatingFile(path, 0, 0)
+	if err != nil {
+		t.Fatalf("error opening file: %v", err)
+	}
+	defer f.Close()
+
+	// Move the file away and put something that can't be opened in its place
+	moved := filepath.Join(dir, "finger.log.old")
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatalf("error moving file: %v", err)
+	}
+
+	if err := os.Mkdir(path, 0o750); err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+
+	if err := f.Reopen(); err == nil {
+		t.Fatal("expected an error reopening file")
+	}
+
+	// Logs keep going to the old file
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Fatalf("error writing: %v", err)
+	}
+
+	if got := readFile(t, moved); got != "after\n" {
+		t.Errorf("expected %q in the old file, got %q", "after\n", got)
+	}
+}
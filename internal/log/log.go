@@ -10,9 +10,10 @@ import (
 
 type loggerCtxKey struct{}
 
-// NewLogger creates a new logger with the given debug level.
+// NewLogger creates a new logger with the format and level of the config.
+// Debug mode logs debug messages and their source.
 func NewLogger(w io.Writer, cfg *config.Config) *slog.Logger {
-	level := slog.LevelInfo
+	level := ParseLevel(cfg.LogLevel)
 	addSource := false
 
 	if cfg.Debug {
@@ -20,12 +21,42 @@ func NewLogger(w io.Writer, cfg *config.Config) *slog.Logger {
 		addSource = true
 	}
 
-	return slog.New(
-		slog.NewJSONHandler(w, &slog.HandlerOptions{
-			Level:     level,
-			AddSource: addSource,
-		}),
-	)
+	opts := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: addSource,
+	}
+
+	// Writers like syslog need to know the level of each record, so
+	// records are formatted into a buffer and sent with their level
+	if lw, ok := w.(levelWriter); ok {
+		rb := &recordBuffer{}
+
+		return slog.New(&levelHandler{Handler: newHandler(rb, cfg, opts), rb: rb, w: lw})
+	}
+
+	return slog.New(newHandler(w, cfg, opts))
+}
+
+func newHandler(w io.Writer, cfg *config.Config, opts *slog.HandlerOptions) slog.Handler {
+	if cfg.LogFormat == config.LogFormatText {
+		return slog.NewTextHandler(w, opts)
+	}
+
+	return slog.NewJSONHandler(w, opts)
+}
+
+// ParseLevel parses a log level name, defaulting to info.
+func ParseLevel(name string) slog.Level {
+	switch name {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
 }
 
 func FromContext(ctx context.Context) *slog.Logger {
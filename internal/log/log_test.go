@@ -66,6 +66,78 @@ func TestNewLogger(t *testing.T) {
 	})
 }
 
+func TestNewLogger_Options(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		format    string
+		level     string
+		wantLines []string
+	}{
+		{
+			name:      "json",
+			format:    config.LogFormatJSON,
+			level:     "info",
+			wantLines: []string{`"msg":"info"`, `"msg":"warn"`, `"msg":"error"`},
+		},
+		{
+			name:      "text",
+			format:    config.LogFormatText,
+			level:     "info",
+			wantLines: []string{"msg=info", "msg=warn", "msg=error"},
+		},
+		{
+			name:      "warn level",
+			format:    config.LogFormatText,
+			level:     "warn",
+			wantLines: []string{"msg=warn", "msg=error"},
+		},
+		{
+			name:      "error level",
+			format:    config.LogFormatJSON,
+			level:     "error",
+			wantLines: []string{`"msg":"error"`},
+		},
+		{
+			name:      "debug level",
+			format:    config.LogFormatText,
+			level:     "debug",
+			wantLines: []string{"msg=debug", "msg=info", "msg=warn", "msg=error"},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.NewConfig()
+			cfg.LogFormat = tc.format
+			cfg.LogLevel = tc.level
+
+			w := &strings.Builder{}
+			l := log.NewLogger(w, cfg)
+
+			l.Debug("debug")
+			l.Info("info")
+			l.Warn("warn")
+			l.Error("error")
+
+			lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+			if len(lines) != len(tc.wantLines) {
+				t.Fatalf("expected %d lines, got %d:\n%s", len(tc.wantLines), len(lines), w.String())
+			}
+
+			for i, want := range tc.wantLines {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("expected %q in line %q", want, lines[i])
+				}
+			}
+		})
+	}
+}
+
 func TestFromContext(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"io"
+	"os"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+)
+
+// bytesPerMegabyte converts the log max size to bytes.
+const bytesPerMegabyte = 1024 * 1024
+
+// syslogTag is the name logs are sent to syslog with.
+const syslogTag = "finger"
+
+// Output is where logs are written to. It can be reopened to follow log
+// files moved by logrotate.
+type Output interface {
+	io.WriteCloser
+	Reopen() error
+}
+
+// OpenOutput opens the log output of the config.
+func OpenOutput(cfg *config.Config) (Output, error) {
+	switch cfg.LogOutput {
+	case "", config.LogOutputStderr:
+		return stdOutput{os.Stderr}, nil
+	case config.LogOutputStdout:
+		return stdOutput{os.Stdout}, nil
+	case config.LogOutputSyslog:
+		network, addr, err := cfg.GetLogSyslogAddr()
+		if err != nil {
+			return nil, err //nolint:wrapcheck // Already a config error
+		}
+
+		return DialSyslog(network, addr, syslogTag)
+	default:
+		return OpenRotatingFile(cfg.LogOutput, int64(cfg.LogMaxSize)*bytesPerMegabyte, cfg.LogMaxBackups)
+	}
+}
+
+// stdOutput writes to the standard output or error, which are never
+// reopened or closed.
+type stdOutput struct {
+	io.Writer
+}
+
+func (stdOutput) Reopen() error {
+	return nil
+}
+
+func (stdOutput) Close() error {
+	return nil
+}
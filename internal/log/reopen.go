@@ -0,0 +1,35 @@
+//go:build !windows
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReopenOnSignal reopens the output every time the process receives a
+// SIGUSR1, until the context is done.
+func ReopenOnSignal(ctx context.Context, o Output) {
+	l := FromContext(ctx)
+
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+
+	defer signal.Stop(usr1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-usr1:
+			if err := o.Reopen(); err != nil {
+				l.Error("Failed to reopen log output", slog.Any("error", err))
+			} else {
+				l.Info("Reopened log output")
+			}
+		}
+	}
+}
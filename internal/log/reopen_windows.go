@@ -0,0 +1,8 @@
+package log
+
+import (
+	"context"
+)
+
+// ReopenOnSignal does nothing on Windows, which has no SIGUSR1.
+func ReopenOnSignal(_ context.Context, _ Output) {}
@@ -0,0 +1,231 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// syslogFacility is the daemon facility.
+const syslogFacility = 3
+
+// Syslog severities.
+const (
+	severityError   = 3
+	severityWarning = 4
+	severityInfo    = 6
+	severityDebug   = 7
+)
+
+// levelWriter is a writer that sends each record along with its level.
+type levelWriter interface {
+	WriteLevel(level slog.Level, p []byte) (int, error)
+}
+
+// recordBuffer holds a single formatted record.
+type recordBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *recordBuffer) Write(p []byte) (int, error) {
+	return b.buf.Write(p) //nolint:wrapcheck // Writing to a buffer never fails
+}
+
+// levelHandler formats records with the wrapped handler and sends them to
+// a levelWriter with their level.
+type levelHandler struct {
+	slog.Handler
+
+	rb *recordBuffer
+	w  levelWriter
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.rb.mu.Lock()
+	defer h.rb.mu.Unlock()
+
+	h.rb.buf.Reset()
+
+	if err := h.Handler.Handle(ctx, r); err != nil {
+		return err //nolint:wrapcheck // Errors are returned as they are by slog handlers
+	}
+
+	if _, err := h.w.WriteLevel(r.Level, h.rb.buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing log: %w", err)
+	}
+
+	return nil
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), rb: h.rb, w: h.w}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), rb: h.rb, w: h.w}
+}
+
+// SyslogWriter sends logs to a syslog daemon over a Unix or UDP socket,
+// in the RFC 3164 format.
+type SyslogWriter struct {
+	network string
+	addr    string
+	tag     string
+
+	mu       sync.Mutex
+	conn     net.Conn
+	hostname string
+}
+
+// DialSyslog connects to the syslog daemon at addr. The network must be
+// "unix" or "udp", and tag is the name logs are sent with.
+func DialSyslog(network, addr, tag string) (*SyslogWriter, error) {
+	w := &SyslogWriter{
+		network: network,
+		addr:    addr,
+		tag:     tag,
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	if w.network != "unix" {
+		conn, err := net.Dial(w.network, w.addr)
+		if err != nil {
+			return fmt.Errorf("error connecting to syslog: %w", err)
+		}
+
+		// Remote daemons need to know where the logs come from
+		w.conn = conn
+		w.hostname, _ = os.Hostname()
+
+		return nil
+	}
+
+	// Local daemons listen on datagram or stream sockets
+	var err error
+
+	for _, network := range []string{"unixgram", "unix"} {
+		var conn net.Conn
+
+		conn, err = net.Dial(network, w.addr)
+		if err == nil {
+			w.conn = conn
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("error connecting to syslog: %w", err)
+}
+
+// Write sends p with the info severity.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(slog.LevelInfo, p)
+}
+
+// WriteLevel sends p with the severity of level.
+func (w *SyslogWriter) WriteLevel(level slog.Level, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msg := w.format(level, p)
+
+	// Reconnect once if the daemon went away
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+	}
+
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+
+	if _, err := w.conn.Write(msg); err != nil {
+		return 0, fmt.Errorf("error writing to syslog: %w", err)
+	}
+
+	return len(p), nil
+}
+
+func (w *SyslogWriter) format(level slog.Level, p []byte) []byte {
+	priority := syslogFacility*8 + severity(level) //nolint:gomnd // Defined by RFC 3164
+
+	msg := make([]byte, 0, len(p)+64) //nolint:gomnd // Room for the header
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(priority), 10)
+	msg = append(msg, '>')
+	msg = time.Now().AppendFormat(msg, time.Stamp)
+	msg = append(msg, ' ')
+
+	if w.hostname != "" {
+		msg = append(msg, w.hostname...)
+		msg = append(msg, ' ')
+	}
+
+	msg = append(msg, w.tag...)
+	msg = append(msg, '[')
+	msg = strconv.AppendInt(msg, int64(os.Getpid()), 10)
+	msg = append(msg, "]: "...)
+	msg = append(msg, bytes.TrimRight(p, "\n")...)
+
+	// Stream sockets need a delimiter between messages
+	return append(msg, '\n')
+}
+
+// Reopen connects to the syslog daemon again.
+func (w *SyslogWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.connect()
+}
+
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+
+	if err != nil {
+		return fmt.Errorf("error closing syslog connection: %w", err)
+	}
+
+	return nil
+}
+
+func severity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return severityError
+	case level >= slog.LevelWarn:
+		return severityWarning
+	case level >= slog.LevelInfo:
+		return severityInfo
+	default:
+		return severityDebug
+	}
+}
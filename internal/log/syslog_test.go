@@ -0,0 +1,60 @@
+package log_test
+
+import (
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/log"
+)
+
+func TestSyslogWriter(t *testing.T) {
+	t.Parallel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	defer conn.Close()
+
+	w, err := log.DialSyslog("udp", conn.LocalAddr().String(), "finger")
+	if err != nil {
+		t.Fatalf("error dialing syslog: %v", err)
+	}
+	defer w.Close()
+
+	cfg := config.NewConfig()
+	cfg.LogFormat = config.LogFormatText
+
+	l := log.NewLogger(w, cfg).With("key", "value")
+
+	l.Info("hello")
+	l.Error("oops")
+
+	read := func() string {
+		buf := make([]byte, 1024)
+
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("error reading: %v", err)
+		}
+
+		return string(buf[:n])
+	}
+
+	// Daemon facility (3) with the info (6) and error (3) severities
+	tests := []*regexp.Regexp{
+		regexp.MustCompile(`^<30>\w{3} [ \d]\d \d\d:\d\d:\d\d \S+ finger\[\d+\]: time=\S+ level=INFO msg=hello key=value\n$`),
+		regexp.MustCompile(`^<27>\w{3} [ \d]\d \d\d:\d\d:\d\d \S+ finger\[\d+\]: time=\S+ level=ERROR msg=oops key=value\n$`),
+	}
+
+	for _, want := range tests {
+		if got := read(); !want.MatchString(got) {
+			t.Errorf("expected message to match %s, got %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/store"
+)
+
+// DurationBuckets are the latency histogram buckets, in seconds.
+var DurationBuckets = []float64{ //nolint:gochecknoglobals // Read-only defaults
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1,
+}
+
+// Metrics are the metrics collected by the server.
+type Metrics struct {
+	Registry *Registry
+
+	requests        *CounterVec
+	requestDuration *Histogram
+	lookups         *CounterVec
+	reloads         *CounterVec
+	bans            atomic.Uint64
+}
+
+func New() *Metrics {
+	r := NewRegistry()
+
+	m := &Metrics{
+		Registry: r,
+		requests: r.NewCounterVec(
+			"finger_http_requests_total",
+			"Total number of HTTP requests by method and status code.",
+			"method", "status",
+		),
+		requestDuration: r.NewHistogram(
+			"finger_http_request_duration_seconds",
+			"Latency of HTTP requests in seconds.",
+			DurationBuckets,
+		),
+		lookups: r.NewCounterVec(
+			"finger_webfinger_lookups_total",
+			"Total number of webfinger lookups by result.",
+			"result",
+		),
+		reloads: r.NewCounterVec(
+			"finger_reloads_total",
+			"Total number of webfinger reloads by result.",
+			"result",
+		),
+	}
+
+	r.NewCounterFunc(
+		"finger_bans_total",
+		"Total number of clients banned for too many misses.",
+		func() float64 {
+			return float64(m.bans.Load())
+		},
+	)
+
+	return m
+}
+
+// ObserveRequest records a served HTTP request.
+func (m *Metrics) ObserveRequest(method string, status int, duration time.Duration) {
+	m.requests.Inc(normalizeMethod(method), strconv.Itoa(status))
+	m.requestDuration.Observe(duration.Seconds())
+}
+
+// ObserveLookup records the result of a webfinger lookup.
+func (m *Metrics) ObserveLookup(result string) {
+	m.lookups.Inc(result)
+}
+
+// ObserveBan records a client being banned.
+func (m *Metrics) ObserveBan() {
+	m.bans.Add(1)
+}
+
+// CollectStore reports the webfingers loaded in the store and its reloads.
+func (m *Metrics) CollectStore(s *store.Store) {
+	m.Registry.NewGaugeFunc(
+		"finger_resources",
+		"Number of webfinger resources currently loaded.",
+		func() float64 {
+			return float64(len(s.Current().Fingers))
+		},
+	)
+	m.Registry.NewGaugeFunc(
+		"finger_last_reload_timestamp_seconds",
+		"Unix time of the last successful webfinger load.",
+		func() float64 {
+			return float64(s.Current().LoadedAt.UnixNano()) / float64(time.Second)
+		},
+	)
+
+	s.OnReload(func(err error) {
+		if err != nil {
+			m.reloads.Inc("failure")
+		} else {
+			m.reloads.Inc("success")
+		}
+	})
+}
+
+// normalizeMethod keeps the method label from growing with arbitrary
+// methods sent by clients.
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,124 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/metrics"
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+func writeMetrics(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	out := &strings.Builder{}
+	if _, err := m.Registry.WriteTo(out); err != nil {
+		t.Fatalf("error writing metrics: %v", err)
+	}
+
+	return out.String()
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("observes requests", func(t *testing.T) {
+		t.Parallel()
+
+		m := metrics.New()
+		m.ObserveRequest("GET", 200, time.Millisecond)
+		m.ObserveRequest("BREW", 405, time.Millisecond)
+
+		out := writeMetrics(t, m)
+
+		for _, want := range []string{
+			`finger_http_requests_total{method="GET",status="200"} 1`,
+			`finger_http_requests_total{method="OTHER",status="405"} 1`,
+			`finger_http_request_duration_seconds_count 2`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("observes lookups", func(t *testing.T) {
+		t.Parallel()
+
+		m := metrics.New()
+		m.ObserveLookup("hit")
+		m.ObserveLookup("miss")
+		m.ObserveLookup("miss")
+
+		out := writeMetrics(t, m)
+
+		for _, want := range []string{
+			`finger_webfinger_lookups_total{result="hit"} 1`,
+			`finger_webfinger_lookups_total{result="miss"} 2`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("observes bans", func(t *testing.T) {
+		t.Parallel()
+
+		m := metrics.New()
+
+		if out := writeMetrics(t, m); !strings.Contains(out, "finger_bans_total 0\n") {
+			t.Errorf("expected no bans in:\n%s", out)
+		}
+
+		m.ObserveBan()
+		m.ObserveBan()
+
+		if out := writeMetrics(t, m); !strings.Contains(out, "finger_bans_total 2\n") {
+			t.Errorf("expected 2 bans in:\n%s", out)
+		}
+	})
+
+	t.Run("collects the store", func(t *testing.T) {
+		t.Parallel()
+
+		fail := false
+		s := store.New(webfingers.WebFingers{
+			"acct:user@example.com": {Subject: "acct:user@example.com"},
+		}, func(_ context.Context) (webfingers.WebFingers, error) {
+			if fail {
+				return nil, errors.New("load failed")
+			}
+
+			return webfingers.WebFingers{}, nil
+		})
+
+		m := metrics.New()
+		m.CollectStore(s)
+
+		if out := writeMetrics(t, m); !strings.Contains(out, "finger_resources 1\n") {
+			t.Errorf("expected 1 resource in:\n%s", out)
+		}
+
+		_ = s.Reload(context.Background())
+		fail = true
+		_ = s.Reload(context.Background())
+
+		out := writeMetrics(t, m)
+
+		for _, want := range []string{
+			"finger_resources 0\n",
+			`finger_reloads_total{result="success"} 1`,
+			`finger_reloads_total{result="failure"} 1`,
+			"finger_last_reload_timestamp_seconds ",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in:\n%s", want, out)
+			}
+		}
+	})
+}
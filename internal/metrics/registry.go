@@ -0,0 +1,313 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// labelSeparator joins label values into map keys. It can't appear in
+// valid UTF-8 label values.
+const labelSeparator = "\xff"
+
+// collector is a metric family that can write itself in the Prometheus
+// text exposition format.
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and exposes them in the Prometheus text format.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo writes every metric in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	for _, c := range collectors {
+		c.write(bw)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return cw.n, fmt.Errorf("error writing metrics: %w", err)
+	}
+
+	return cw.n, nil
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.RWMutex
+	values map[string]*atomic.Uint64
+}
+
+// NewCounterVec creates and registers a new counter with the given labels.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]*atomic.Uint64),
+	}
+
+	r.register(c)
+
+	return c
+}
+
+// Inc increments the counter with the given label values, which must be
+// given in the same order as the labels.
+func (c *CounterVec) Inc(labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+
+	c.mu.RLock()
+	v, ok := c.values[key]
+	c.mu.RUnlock()
+
+	if !ok {
+		c.mu.Lock()
+		if v, ok = c.values[key]; !ok {
+			v = &atomic.Uint64{}
+			c.values[key] = v
+		}
+		c.mu.Unlock()
+	}
+
+	v.Add(1)
+}
+
+// Get returns the current value of the counter with the given label values.
+func (c *CounterVec) Get(labelValues ...string) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if v, ok := c.values[strings.Join(labelValues, labelSeparator)]; ok {
+		return v.Load()
+	}
+
+	return 0
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+
+	c.mu.RLock()
+	keys := make([]string, 0, len(c.values))
+
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	c.mu.RUnlock()
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		c.mu.RLock()
+		v := c.values[key].Load()
+		c.mu.RUnlock()
+
+		writeSample(w, c.name, formatLabels(c.labels, strings.Split(key, labelSeparator)), float64(v))
+	}
+}
+
+// Histogram counts observations in configurable buckets.
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+
+	counts []atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Uint64 // float64 bits
+}
+
+// NewHistogram creates and registers a new histogram with the given
+// bucket upper bounds, which must be sorted.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]atomic.Uint64, len(buckets)),
+	}
+
+	r.register(h)
+
+	return h
+}
+
+// Observe adds an observation to the histogram.
+func (h *Histogram) Observe(v float64) {
+	// Only the first matching bucket is incremented, they are
+	// accumulated when written.
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		h.counts[i].Add(1)
+	}
+
+	h.count.Add(1)
+
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+
+	var cumulative uint64
+
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i].Load()
+
+		writeSample(w, h.name+"_bucket", `{le="`+formatFloat(bound)+`"}`, float64(cumulative))
+	}
+
+	count := h.count.Load()
+
+	writeSample(w, h.name+"_bucket", `{le="+Inf"}`, float64(count))
+	writeSample(w, h.name+"_sum", "", math.Float64frombits(h.sum.Load()))
+	writeSample(w, h.name+"_count", "", float64(count))
+}
+
+// funcMetric is a gauge or counter whose value is read when written.
+type funcMetric struct {
+	name  string
+	help  string
+	kind  string
+	value func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is given by fn.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{name: name, help: help, kind: "gauge", value: fn})
+}
+
+// NewCounterFunc registers a counter whose value is given by fn.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{name: name, help: help, kind: "counter", value: fn})
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, m.name, m.help, m.kind)
+	writeSample(w, m.name, "", m.value())
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	w.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name + labels + " " + formatFloat(value) + "\n")
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+
+	b.WriteByte('{')
+
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+
+		b.WriteString(name + `="` + escapeLabel(value) + `"`)
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+
+	return n, err //nolint:wrapcheck // Wrapped by WriteTo
+}
@@ -0,0 +1,108 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/metrics"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	r := metrics.NewRegistry()
+
+	c := r.NewCounterVec("test_total", "A test counter.", "method", "status")
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Inc("POST", "404")
+
+	h := r.NewHistogram("test_seconds", "A test histogram.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	r.NewGaugeFunc("test_gauge", "A test gauge.", func() float64 { return 42 })
+	r.NewCounterFunc("test_func_total", "A test counter func.", func() float64 { return 7 })
+
+	escaped := r.NewCounterVec("test_escaped_total", "Help with a \\ and\na newline.", "value")
+	escaped.Inc("a \"quoted\"\nvalue")
+
+	out := &strings.Builder{}
+	if _, err := r.WriteTo(out); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := `# HELP test_total A test counter.
+# TYPE test_total counter
+test_total{method="GET",status="200"} 2
+test_total{method="POST",status="404"} 1
+# HELP test_seconds A test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.1"} 1
+test_seconds_bucket{le="1"} 2
+test_seconds_bucket{le="+Inf"} 3
+test_seconds_sum 5.55
+test_seconds_count 3
+# HELP test_gauge A test gauge.
+# TYPE test_gauge gauge
+test_gauge 42
+# HELP test_func_total A test counter func.
+# TYPE test_func_total counter
+test_func_total 7
+# HELP test_escaped_total Help with a \\ and\na newline.
+# TYPE test_escaped_total counter
+test_escaped_total{value="a \"quoted\"\nvalue"} 1
+`
+
+	if out.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out.String())
+	}
+
+	if got := c.Get("GET", "200"); got != 2 {
+		t.Errorf("expected counter value 2, got %d", got)
+	}
+
+	if got := h.Count(); got != 3 {
+		t.Errorf("expected histogram count 3, got %d", got)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	t.Parallel()
+
+	r := metrics.NewRegistry()
+	r.NewGaugeFunc("test_gauge", "A test gauge.", func() float64 { return 1 })
+
+	t.Run("serves metrics", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+			t.Errorf("unexpected content type %s", w.Header().Get("Content-Type"))
+		}
+
+		if !strings.Contains(w.Body.String(), "test_gauge 1\n") {
+			t.Errorf("expected metrics in body, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("rejects other methods", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/metrics", http.NoBody))
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status code %d, got %d", http.StatusMethodNotAllowed, w.Code)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/ban"
+	"git.maronato.dev/maronato/finger/internal/clientip"
+)
+
+// Ban counts the 404 responses of each client IP with the given tracker.
+// Banned clients get a 429 with a Retry-After header or, if tarpit is
+// positive, have their responses delayed by it instead.
+func Ban(t *ban.Tracker, tarpit time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := clientip.FromRequest(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			key := ip.String()
+
+			if banned, remaining := t.Banned(key); banned {
+				if tarpit <= 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+					http.Error(w, "Too many requests", http.StatusTooManyRequests)
+
+					return
+				}
+
+				// Slow the client down, but still answer it
+				timer := time.NewTimer(tarpit)
+
+				select {
+				case <-r.Context().Done():
+					timer.Stop()
+
+					return
+				case <-timer.C:
+				}
+
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			// Wrap the response writer
+			wrapped := WrapResponseWriter(w)
+
+			// Call the next handler
+			next.ServeHTTP(wrapped, r)
+
+			if wrapped.Status() == http.StatusNotFound {
+				t.Miss(key)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/ban"
+	"git.maronato.dev/maronato/finger/internal/middleware"
+)
+
+func TestBan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		tarpit     time.Duration
+		wantStatus int
+		wantRetry  string
+	}{
+		{
+			name:       "block",
+			tarpit:     0,
+			wantStatus: http.StatusTooManyRequests,
+			wantRetry:  "600",
+		},
+		{
+			name:       "tarpit",
+			tarpit:     time.Millisecond * 20,
+			wantStatus: http.StatusOK,
+			wantRetry:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+			tr := ban.New(2, time.Minute, 10*time.Minute, ban.WithClock(func() time.Time { return now }))
+
+			h := middleware.Ban(tr, tc.tarpit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/missing" {
+					http.NotFound(w, r)
+				}
+			}))
+
+			do := func(remoteAddr, path string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+				r.RemoteAddr = remoteAddr
+
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				return w
+			}
+
+			// Found resources don't count
+			for i := 0; i < 3; i++ {
+				do("192.0.2.1:1234", "/")
+			}
+
+			if banned, _ := tr.Banned("192.0.2.1"); banned {
+				t.Fatal("expected client not to be banned")
+			}
+
+			// Misses do
+			do("192.0.2.1:1234", "/missing")
+			do("192.0.2.1:1234", "/missing")
+
+			if banned, _ := tr.Banned("192.0.2.1"); !banned {
+				t.Fatal("expected client to be banned")
+			}
+
+			start := time.Now()
+			w := do("192.0.2.1:1234", "/")
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("expected status code %d, got %d", tc.wantStatus, w.Code)
+			}
+
+			if got := w.Header().Get("Retry-After"); got != tc.wantRetry {
+				t.Errorf("expected Retry-After %q, got %q", tc.wantRetry, got)
+			}
+
+			if elapsed := time.Since(start); elapsed < tc.tarpit {
+				t.Errorf("expected response to be delayed by %v, got %v", tc.tarpit, elapsed)
+			}
+
+			// Other clients are not affected
+			if w := do("192.0.2.2:1234", "/"); w.Code != http.StatusOK {
+				t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+		})
+	}
+}
+
+func TestBan_TarpitCanceled(t *testing.T) {
+	t.Parallel()
+
+	tr := ban.New(1, time.Minute, time.Hour)
+	tr.Miss("192.0.2.1")
+
+	called := false
+	h := middleware.Ban(tr, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	// Clients that go away aren't held for the whole tarpit
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody).WithContext(ctx)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	done := make(chan struct{})
+
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the tarpit to stop when the request is canceled")
+	}
+
+	if called {
+		t.Error("expected canceled requests not to be answered")
+	}
+}
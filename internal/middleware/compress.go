@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"git.maronato.dev/maronato/finger/internal/negotiate"
+)
+
+var gzipWriterPool = sync.Pool{ //nolint:gochecknoglobals // Pool is shared by all requests
+	New: func() any {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// Compress gzips responses of at least minSize bytes for clients that
+// accept it. Responses that already have a Content-Encoding, such as the
+// pre-compressed webfinger responses, are sent as they are.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The response depends on the Accept-Encoding header
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if r.Method == http.MethodHead || !negotiate.AcceptsEncoding(r, "gzip") {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, minSize: minSize}
+			defer cw.close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter buffers the start of a response until it knows whether
+// it is large enough to be worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+
+	minSize     int
+	status      int
+	buf         []byte
+	decided     bool
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+
+	w.status = code
+
+	// Responses without a body can be sent right away
+	if code == http.StatusNoContent || code == http.StatusNotModified || code < http.StatusOK {
+		w.decided = true
+		w.flushHeader()
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if !w.decided {
+		// Already encoded responses are sent as is
+		if w.Header().Get("Content-Encoding") != "" {
+			w.decided = true
+			w.flushHeader()
+		} else {
+			w.buf = append(w.buf, b...)
+			if len(w.buf) < w.minSize {
+				return len(b), nil
+			}
+
+			w.startGzip()
+
+			if _, err := w.gz.Write(w.buf); err != nil {
+				return 0, fmt.Errorf("error compressing response: %w", err)
+			}
+
+			w.buf = nil
+
+			return len(b), nil
+		}
+	}
+
+	if w.gz != nil {
+		if _, err := w.gz.Write(b); err != nil {
+			return 0, fmt.Errorf("error compressing response: %w", err)
+		}
+
+		return len(b), nil
+	}
+
+	size, err := w.ResponseWriter.Write(b)
+	if err != nil {
+		return 0, fmt.Errorf("error writing response: %w", err)
+	}
+
+	return size, nil
+}
+
+func (w *compressWriter) startGzip() {
+	w.decided = true
+
+	h := w.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+
+	// The compressed body is no longer byte-for-byte equal to the
+	// original, so a strong ETag must become weak
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		h.Set("ETag", "W/"+etag)
+	}
+
+	w.flushHeader()
+
+	gz, _ := gzipWriterPool.Get().(*gzip.Writer)
+	gz.Reset(w.ResponseWriter)
+	w.gz = gz
+}
+
+func (w *compressWriter) flushHeader() {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// close sends whatever is still buffered and finishes the gzip stream.
+func (w *compressWriter) close() {
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriterPool.Put(w.gz)
+		w.gz = nil
+
+		return
+	}
+
+	// The handler never wrote anything
+	if w.status == 0 && len(w.buf) == 0 {
+		return
+	}
+
+	w.flushHeader()
+
+	if len(w.buf) > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf)
+	}
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
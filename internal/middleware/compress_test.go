@@ -0,0 +1,190 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/middleware"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	large := strings.Repeat("a", 100)
+	small := "small"
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		method         string
+		body           string
+		status         int
+		headers        map[string]string
+		wantGzip       bool
+		wantETag       string
+	}{
+		{
+			name:           "compresses large responses",
+			acceptEncoding: "gzip",
+			body:           large,
+			wantGzip:       true,
+		},
+		{
+			name:           "skips small responses",
+			acceptEncoding: "gzip",
+			body:           small,
+			wantGzip:       false,
+		},
+		{
+			name:     "skips clients without gzip",
+			body:     large,
+			wantGzip: false,
+		},
+		{
+			name:           "skips clients that refuse gzip",
+			acceptEncoding: "gzip;q=0",
+			body:           large,
+			wantGzip:       false,
+		},
+		{
+			name:           "skips encoded responses",
+			acceptEncoding: "gzip",
+			body:           large,
+			headers:        map[string]string{"Content-Encoding": "br"},
+			wantGzip:       false,
+		},
+		{
+			name:           "skips HEAD requests",
+			acceptEncoding: "gzip",
+			method:         http.MethodHead,
+			wantGzip:       false,
+		},
+		{
+			name:           "keeps status codes",
+			acceptEncoding: "gzip",
+			body:           large,
+			status:         http.StatusNotFound,
+			wantGzip:       true,
+		},
+		{
+			name:           "weakens strong etags",
+			acceptEncoding: "gzip",
+			body:           large,
+			headers:        map[string]string{"ETag": `"abc"`},
+			wantGzip:       true,
+			wantETag:       `W/"abc"`,
+		},
+		{
+			name:           "keeps etags of uncompressed responses",
+			acceptEncoding: "gzip",
+			body:           small,
+			headers:        map[string]string{"ETag": `"abc"`},
+			wantGzip:       false,
+			wantETag:       `"abc"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			status := tc.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			r := httptest.NewRequest(method, "/", http.NoBody)
+			if tc.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+
+			w := httptest.NewRecorder()
+
+			middleware.Compress(50)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tc.headers {
+					w.Header().Set(k, v)
+				}
+
+				w.WriteHeader(status)
+
+				// Write in chunks to exercise the buffering
+				for i := 0; i < len(tc.body); i += 10 {
+					_, _ = w.Write([]byte(tc.body[i:min(i+10, len(tc.body))]))
+				}
+			})).ServeHTTP(w, r)
+
+			if w.Code != status {
+				t.Errorf("expected status %d, got %d", status, w.Code)
+			}
+
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+			}
+
+			if tc.wantETag != "" && w.Header().Get("ETag") != tc.wantETag {
+				t.Errorf("expected ETag %s, got %s", tc.wantETag, w.Header().Get("ETag"))
+			}
+
+			isGzip := w.Header().Get("Content-Encoding") == "gzip"
+			if isGzip != tc.wantGzip {
+				t.Fatalf("expected gzip %v, got Content-Encoding %q", tc.wantGzip, w.Header().Get("Content-Encoding"))
+			}
+
+			body := w.Body.String()
+
+			if isGzip {
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("error reading gzip: %v", err)
+				}
+
+				b, err := io.ReadAll(gz)
+				if err != nil {
+					t.Fatalf("error reading gzip: %v", err)
+				}
+
+				body = string(b)
+			}
+
+			if body != tc.body {
+				t.Errorf("expected body %q, got %q", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestCompress_NotModified(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	w := httptest.NewRecorder()
+
+	middleware.Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	})).ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected no Content-Encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body.String())
+	}
+}
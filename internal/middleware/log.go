@@ -1,13 +1,40 @@
 package middleware
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
+	"git.maronato.dev/maronato/finger/internal/clientip"
 	"git.maronato.dev/maronato/finger/internal/log"
 )
 
+type logAttrsCtxKey struct{}
+
+// logAttrs are the attributes handlers add to the request log.
+type logAttrs struct {
+	mu    sync.Mutex
+	attrs []any
+}
+
+// AddLogAttrs adds attributes to the log line of the request. It does
+// nothing if the request is not logged.
+func AddLogAttrs(ctx context.Context, attrs ...slog.Attr) {
+	la, ok := ctx.Value(logAttrsCtxKey{}).(*logAttrs)
+	if !ok {
+		return
+	}
+
+	la.mu.Lock()
+	defer la.mu.Unlock()
+
+	for _, attr := range attrs {
+		la.attrs = append(la.attrs, attr)
+	}
+}
+
 func RequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -15,6 +42,10 @@ func RequestLogger(next http.Handler) http.Handler {
 
 		start := time.Now()
 
+		// Let handlers add their own attributes
+		la := &logAttrs{}
+		r = r.WithContext(context.WithValue(ctx, logAttrsCtxKey{}, la))
+
 		// Wrap the response writer
 		wrapped := WrapResponseWriter(w)
 
@@ -23,15 +54,25 @@ func RequestLogger(next http.Handler) http.Handler {
 
 		status := wrapped.Status()
 
+		// Log the client IP if known
+		remote := r.RemoteAddr
+		if ip, ok := clientip.FromContext(ctx); ok {
+			remote = ip.String()
+		}
+
 		// Log the request
 		lg := l.With(
 			slog.String("method", r.Method),
 			slog.String("path", r.URL.Path),
 			slog.Int("status", status),
-			slog.String("remote", r.RemoteAddr),
+			slog.String("remote", remote),
 			slog.Duration("duration", time.Since(start)),
 		)
 
+		la.mu.Lock()
+		lg = lg.With(la.attrs...)
+		la.mu.Unlock()
+
 		switch {
 		case status >= http.StatusInternalServerError:
 			lg.Error("Server error")
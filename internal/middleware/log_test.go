@@ -2,11 +2,14 @@ package middleware_test
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"strings"
 	"testing"
 
+	"git.maronato.dev/maronato/finger/internal/clientip"
 	"git.maronato.dev/maronato/finger/internal/config"
 	"git.maronato.dev/maronato/finger/internal/log"
 	"git.maronato.dev/maronato/finger/internal/middleware"
@@ -42,3 +45,54 @@ func TestRequestLogger(t *testing.T) {
 		t.Error("logger did not log request")
 	}
 }
+
+func TestRequestLogger_ClientIP(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := config.NewConfig()
+
+	stdout := &strings.Builder{}
+
+	l := log.NewLogger(stdout, cfg)
+	ctx = log.WithLogger(ctx, l)
+
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", http.NoBody)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	middleware.RealIP(trusted, clientip.HeaderXForwardedFor, false)(middleware.RequestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))).ServeHTTP(httptest.NewRecorder(), r)
+
+	if !strings.Contains(stdout.String(), `"remote":"198.51.100.1"`) {
+		t.Errorf("expected the client IP to be logged, got %s", stdout.String())
+	}
+}
+
+func TestRequestLogger_AddLogAttrs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := config.NewConfig()
+
+	stdout := &strings.Builder{}
+
+	l := log.NewLogger(stdout, cfg)
+	ctx = log.WithLogger(ctx, l)
+
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", http.NoBody)
+
+	// Adding attributes outside of a logged request does nothing
+	middleware.AddLogAttrs(ctx, slog.String("ignored", "value"))
+
+	middleware.RequestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		middleware.AddLogAttrs(r.Context(), slog.String("resource", "acct:user@example.com"))
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	if !strings.Contains(stdout.String(), `"resource":"acct:user@example.com"`) {
+		t.Errorf("expected the handler attributes to be logged, got %s", stdout.String())
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/metrics"
+)
+
+// Metrics records the method, status and latency of every request.
+func Metrics(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// Wrap the response writer
+			wrapped := WrapResponseWriter(w)
+
+			// Call the next handler
+			next.ServeHTTP(wrapped, r)
+
+			// Handlers that never write anything send a 200
+			status := wrapped.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			m.ObserveRequest(r.Method, status, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/metrics"
+	"git.maronato.dev/maronato/finger/internal/middleware"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := metrics.New()
+
+	h := middleware.Metrics(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	for _, path := range []string{"/", "/", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, http.NoBody))
+	}
+
+	out := &strings.Builder{}
+	if _, err := m.Registry.WriteTo(out); err != nil {
+		t.Fatalf("error writing metrics: %v", err)
+	}
+
+	for _, want := range []string{
+		`finger_http_requests_total{method="GET",status="200"} 2`,
+		`finger_http_requests_total{method="GET",status="404"} 1`,
+		`finger_http_request_duration_seconds_count 3`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+}
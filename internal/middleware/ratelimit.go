@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"git.maronato.dev/maronato/finger/internal/clientip"
+	"git.maronato.dev/maronato/finger/internal/ratelimit"
+)
+
+// ipv6PrefixBits is the size of the IPv6 networks limited together, since
+// a single client usually has a whole /64.
+const ipv6PrefixBits = 64
+
+// RateLimit limits the requests of each client IP with the given limiter.
+// IPv6 clients are limited by their /64. Clients in the allowlist, and
+// clients whose IP is unknown, are never limited. Limited requests get a
+// 429 with a Retry-After header.
+func RateLimit(l *ratelimit.Limiter, allowlist []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := clientip.FromRequest(r)
+			if !ok || containsIP(allowlist, ip) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if allowed, wait := l.Allow(rateLimitKey(ip)); !allowed {
+				// Round up so clients don't retry too early
+				retryAfter := int(math.Ceil(wait.Seconds()))
+				if retryAfter < 1 {
+					retryAfter = 1
+				}
+
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey returns the key of the bucket of ip.
+func rateLimitKey(ip netip.Addr) string {
+	ip = ip.Unmap()
+	if ip.Is4() {
+		return ip.String()
+	}
+
+	prefix, _ := ip.Prefix(ipv6PrefixBits)
+
+	return prefix.String()
+}
+
+// containsIP reports whether any of the prefixes contains ip.
+func containsIP(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/middleware"
+	"git.maronato.dev/maronato/finger/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := ratelimit.New(0.5, 2, ratelimit.WithClock(func() time.Time { return now }))
+	allowlist := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	h := middleware.RateLimit(l, allowlist)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		r.RemoteAddr = remoteAddr
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantStatus int
+		wantRetry  string
+	}{
+		{
+			name:       "first request",
+			remoteAddr: "192.0.2.1:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "second request from another port",
+			remoteAddr: "192.0.2.1:5678",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "over the burst",
+			remoteAddr: "192.0.2.1:1234",
+			wantStatus: http.StatusTooManyRequests,
+			wantRetry:  "2",
+		},
+		{
+			name:       "ipv4 mapped address shares the bucket",
+			remoteAddr: "[::ffff:192.0.2.1]:1234",
+			wantStatus: http.StatusTooManyRequests,
+			wantRetry:  "2",
+		},
+		{
+			name:       "another client",
+			remoteAddr: "192.0.2.2:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "ipv6 client",
+			remoteAddr: "[2001:db8::1]:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "ipv6 client from another address",
+			remoteAddr: "[2001:db8::2]:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "ipv6 addresses of a /64 share the bucket",
+			remoteAddr: "[2001:db8::ffff:1]:1234",
+			wantStatus: http.StatusTooManyRequests,
+			wantRetry:  "2",
+		},
+		{
+			name:       "another ipv6 network",
+			remoteAddr: "[2001:db8:0:1::1]:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "allowlisted client",
+			remoteAddr: "10.1.2.3:1234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown client",
+			remoteAddr: "@",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	// Requests share the limiter, so they must run in order
+	for _, tc := range tests {
+		w := do(tc.remoteAddr)
+
+		if w.Code != tc.wantStatus {
+			t.Errorf("%s: expected status code %d, got %d", tc.name, tc.wantStatus, w.Code)
+		}
+
+		if got := w.Header().Get("Retry-After"); got != tc.wantRetry {
+			t.Errorf("%s: expected Retry-After %q, got %q", tc.name, tc.wantRetry, got)
+		}
+	}
+
+	// Allowlisted clients are never limited
+	for i := 0; i < 5; i++ {
+		if w := do("10.1.2.3:1234"); w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+
+	// And neither are clients without an IP
+	for i := 0; i < 5; i++ {
+		if w := do("@"); w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+}
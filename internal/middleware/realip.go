@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+
+	"git.maronato.dev/maronato/finger/internal/clientip"
+)
+
+// RealIP finds the IP of the client behind the trusted proxies, using the
+// header they set, and stores it in the request context, where it's used
+// by the other middlewares. The header of requests over a Unix socket is
+// only trusted if trustSocket is true.
+func RealIP(trusted []netip.Prefix, header string, trustSocket bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := clientip.Resolve(r, trusted, header, trustSocket); ok {
+				r = r.WithContext(clientip.WithIP(r.Context(), ip))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
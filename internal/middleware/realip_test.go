@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/clientip"
+	"git.maronato.dev/maronato/finger/internal/middleware"
+)
+
+func TestRealIP(t *testing.T) {
+	t.Parallel()
+
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		socket     bool
+		want       string
+	}{
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  "198.51.100.1",
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "untrusted peer",
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  "198.51.100.1",
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "untrusted unix socket",
+			remoteAddr: "@",
+			forwarded:  "198.51.100.1",
+			want:       "invalid IP",
+		},
+		{
+			name:       "trusted unix socket",
+			remoteAddr: "@",
+			forwarded:  "198.51.100.1",
+			socket:     true,
+			want:       "198.51.100.1",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.RemoteAddr = tc.remoteAddr
+			r.Header.Set("X-Forwarded-For", tc.forwarded)
+
+			var got string
+
+			middleware.RealIP(trusted, clientip.HeaderXForwardedFor, tc.socket)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip, _ := clientip.FromContext(r.Context())
+				got = ip.String()
+			})).ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tc.want {
+				t.Errorf("expected client IP %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
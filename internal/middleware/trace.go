@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/internal/tracing"
+)
+
+const (
+	// RequestIDHeader is the header with the ID of the request.
+	RequestIDHeader = "X-Request-ID"
+	// TraceParentHeader is the W3C trace context header.
+	TraceParentHeader = "Traceparent"
+)
+
+// Trace accepts the request ID sent by the client, or generates one, and
+// continues the client's W3C trace, or starts a new one. Both are added to
+// the request logger and echoed in the response headers.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !tracing.ValidRequestID(id) {
+			id = tracing.NewRequestID()
+		}
+
+		attrs := []any{slog.String("request_id", id)}
+
+		// Create a span for this request in the client's trace
+		span := tracing.NewTraceParent()
+
+		if parent, err := tracing.ParseTraceParent(r.Header.Get(TraceParentHeader)); err == nil {
+			span = parent.Child()
+
+			attrs = append(attrs, slog.String("parent_id", parent.SpanIDString()))
+		}
+
+		attrs = append(attrs,
+			slog.String("trace_id", span.TraceIDString()),
+			slog.String("span_id", span.SpanIDString()),
+		)
+
+		ctx = tracing.WithRequestID(ctx, id)
+		ctx = tracing.WithTraceParent(ctx, span)
+		ctx = log.WithLogger(ctx, log.FromContext(ctx).With(attrs...))
+
+		h := w.Header()
+		h.Set(RequestIDHeader, id)
+		h.Set(TraceParentHeader, span.String())
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
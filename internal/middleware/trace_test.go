@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/internal/middleware"
+	"git.maronato.dev/maronato/finger/internal/tracing"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name          string
+		requestID     string
+		traceParent   string
+		wantRequestID string
+		wantTraceID   string
+	}{
+		{
+			name:          "continues the client's trace",
+			requestID:     "ingress-123",
+			traceParent:   parent,
+			wantRequestID: "ingress-123",
+			wantTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:        "starts a new trace",
+			requestID:   "",
+			traceParent: "",
+		},
+		{
+			name:        "replaces invalid values",
+			requestID:   "bad id\n",
+			traceParent: "00-zzz-01",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout := &strings.Builder{}
+			ctx := log.WithLogger(context.Background(), log.NewLogger(stdout, config.NewConfig()))
+
+			r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", http.NoBody)
+			if tc.requestID != "" {
+				r.Header.Set(middleware.RequestIDHeader, tc.requestID)
+			}
+
+			if tc.traceParent != "" {
+				r.Header.Set(middleware.TraceParentHeader, tc.traceParent)
+			}
+
+			var gotRequestID string
+
+			w := httptest.NewRecorder()
+
+			// Panics are logged with the request's IDs
+			middleware.Trace(middleware.Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRequestID, _ = tracing.RequestIDFromContext(r.Context())
+
+				panic("test")
+			}))).ServeHTTP(w, r)
+
+			requestID := w.Header().Get(middleware.RequestIDHeader)
+			if !tracing.ValidRequestID(requestID) {
+				t.Fatalf("expected a valid request ID, got %q", requestID)
+			}
+
+			if tc.wantRequestID != "" && requestID != tc.wantRequestID {
+				t.Errorf("expected request ID %q, got %q", tc.wantRequestID, requestID)
+			}
+
+			if gotRequestID != requestID {
+				t.Errorf("expected request ID %q in the context, got %q", requestID, gotRequestID)
+			}
+
+			span, err := tracing.ParseTraceParent(w.Header().Get(middleware.TraceParentHeader))
+			if err != nil {
+				t.Fatalf("expected a valid traceparent, got %v", err)
+			}
+
+			if tc.wantTraceID != "" && span.TraceIDString() != tc.wantTraceID {
+				t.Errorf("expected trace ID %q, got %q", tc.wantTraceID, span.TraceIDString())
+			}
+
+			if span.String() == tc.traceParent {
+				t.Error("expected a new span ID")
+			}
+
+			for _, want := range []string{
+				`"msg":"Panic"`,
+				`"request_id":"` + requestID + `"`,
+				`"trace_id":"` + span.TraceIDString() + `"`,
+				`"span_id":"` + span.SpanIDString() + `"`,
+			} {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("expected %s in the logs, got %s", want, stdout.String())
+				}
+			}
+		})
+	}
+}
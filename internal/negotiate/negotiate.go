@@ -0,0 +1,49 @@
+package negotiate
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AcceptsEncoding reports whether the request's Accept-Encoding header
+// allows the response to be sent with the given content coding.
+func AcceptsEncoding(r *http.Request, coding string) bool {
+	accepted := false
+
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for header != "" {
+			var value string
+
+			value, header, _ = strings.Cut(header, ",")
+
+			name, params, _ := strings.Cut(value, ";")
+			name = strings.TrimSpace(name)
+
+			if !strings.EqualFold(name, coding) && name != "*" {
+				continue
+			}
+
+			// An explicit q=0 means the coding is not acceptable
+			q := 1.0
+
+			if key, qvalue, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(qvalue), 64)
+				if err != nil {
+					continue
+				}
+
+				q = parsed
+			}
+
+			// The coding's own entry takes precedence over the wildcard
+			if strings.EqualFold(name, coding) {
+				return q > 0
+			}
+
+			accepted = q > 0
+		}
+	}
+
+	return accepted
+}
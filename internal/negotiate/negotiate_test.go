@@ -0,0 +1,87 @@
+package negotiate_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/negotiate"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{
+			name:   "no header",
+			header: "",
+			want:   false,
+		},
+		{
+			name:   "gzip",
+			header: "gzip",
+			want:   true,
+		},
+		{
+			name:   "gzip in list",
+			header: "deflate, gzip, br",
+			want:   true,
+		},
+		{
+			name:   "case insensitive",
+			header: "GZIP",
+			want:   true,
+		},
+		{
+			name:   "gzip with q",
+			header: "gzip;q=0.5",
+			want:   true,
+		},
+		{
+			name:   "gzip disabled",
+			header: "gzip;q=0, br",
+			want:   false,
+		},
+		{
+			name:   "wildcard",
+			header: "*",
+			want:   true,
+		},
+		{
+			name:   "wildcard with gzip disabled",
+			header: "*, gzip;q=0",
+			want:   false,
+		},
+		{
+			name:   "other codings only",
+			header: "br, deflate",
+			want:   false,
+		},
+		{
+			name:   "invalid q",
+			header: "gzip;q=abc",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tc.header != "" {
+				r.Header.Set("Accept-Encoding", tc.header)
+			}
+
+			if got := negotiate.AcceptsEncoding(r, "gzip"); got != tc.want {
+				t.Errorf("AcceptsEncoding() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Mode is how sensitive values are logged.
+type Mode string
+
+const (
+	// ModePlain logs values as they are.
+	ModePlain Mode = "plain"
+	// ModeHash logs a keyed hash of values, so equal values can still be
+	// matched without revealing them.
+	ModeHash Mode = "hash"
+	// ModeNone doesn't log values at all.
+	ModeNone Mode = "none"
+)
+
+// hashSize is the number of bytes of the hash that are kept.
+const hashSize = 16
+
+// Redactor hides sensitive values according to a mode.
+type Redactor struct {
+	mode Mode
+	key  []byte
+}
+
+// NewRedactor creates a redactor with the given mode. Hashes are keyed
+// with key, or with a random key if it's empty, in which case they only
+// match within the same process.
+func NewRedactor(mode Mode, key string) *Redactor {
+	r := &Redactor{
+		mode: mode,
+		key:  []byte(key),
+	}
+
+	if mode == ModeHash && key == "" {
+		r.key = make([]byte, sha256.Size)
+		_, _ = rand.Read(r.key)
+	}
+
+	return r
+}
+
+// Redact returns the value to be logged in place of v, and false if
+// nothing should be logged.
+func (r *Redactor) Redact(v string) (string, bool) {
+	switch r.mode {
+	case ModeNone:
+		return "", false
+	case ModeHash:
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(v))
+
+		return hex.EncodeToString(mac.Sum(nil)[:hashSize]), true
+	default:
+		return v, true
+	}
+}
@@ -0,0 +1,62 @@
+package privacy_test
+
+import (
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/privacy"
+)
+
+func TestRedactor(t *testing.T) {
+	t.Parallel()
+
+	const value = "acct:user@example.com"
+
+	t.Run("plain", func(t *testing.T) {
+		t.Parallel()
+
+		got, ok := privacy.NewRedactor(privacy.ModePlain, "").Redact(value)
+		if !ok || got != value {
+			t.Errorf("expected %q, got %q %v", value, got, ok)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+
+		if got, ok := privacy.NewRedactor(privacy.ModeNone, "").Redact(value); ok {
+			t.Errorf("expected nothing, got %q", got)
+		}
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		t.Parallel()
+
+		r := privacy.NewRedactor(privacy.ModeHash, "secret")
+
+		got, ok := r.Redact(value)
+		if !ok || got == value || len(got) != 32 {
+			t.Fatalf("expected a 32 character hash, got %q %v", got, ok)
+		}
+
+		// Equal values get the same hash
+		if again, _ := r.Redact(value); again != got {
+			t.Errorf("expected %q, got %q", got, again)
+		}
+
+		// Which depends on the key
+		if other, _ := privacy.NewRedactor(privacy.ModeHash, "other").Redact(value); other == got {
+			t.Error("expected another key to give another hash")
+		}
+	})
+
+	t.Run("hash with a random key", func(t *testing.T) {
+		t.Parallel()
+
+		a, _ := privacy.NewRedactor(privacy.ModeHash, "").Redact(value)
+		b, _ := privacy.NewRedactor(privacy.ModeHash, "").Redact(value)
+
+		if a == b {
+			t.Error("expected random keys to give different hashes")
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// CleanupInterval is how often stale buckets are removed.
+	CleanupInterval = time.Minute
+	// DefaultMaxBuckets is the default number of buckets tracked at once.
+	DefaultMaxBuckets = 100_000
+)
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithClock makes the limiter read the time from now instead of
+// time.Now. It's useful for tests.
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) {
+		l.now = now
+	}
+}
+
+// WithMaxBuckets limits the number of buckets tracked at once. When a new
+// key would go over it, the bucket used least recently is dropped.
+func WithMaxBuckets(n int) Option {
+	return func(l *Limiter) {
+		l.maxBuckets = n
+	}
+}
+
+// bucket is a token bucket of a single client.
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket rate limiter keyed by client.
+type Limiter struct {
+	rate       float64
+	burst      float64
+	now        func() time.Time
+	maxBuckets int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// recent has the buckets from the most to the least recently used.
+	recent *list.List
+}
+
+// New creates a limiter that allows rate requests per second per key,
+// with bursts of up to burst requests.
+func New(rate float64, burst int, opts ...Option) *Limiter {
+	l := &Limiter{
+		rate:       rate,
+		burst:      float64(burst),
+		now:        time.Now,
+		maxBuckets: DefaultMaxBuckets,
+		buckets:    make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Allow takes a token from the bucket of key. If the bucket is empty, it
+// returns false and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, now)
+
+	// Refill the tokens since the last request
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+
+	return false, wait
+}
+
+// bucket returns the bucket of key, creating it if needed.
+func (l *Limiter) bucket(key string, now time.Time) *bucket {
+	if e, ok := l.buckets[key]; ok {
+		l.recent.MoveToFront(e)
+
+		return e.Value.(*bucket) //nolint:forcetypeassert // Always a bucket
+	}
+
+	// Keep memory bounded when many clients show up between cleanups
+	for l.maxBuckets > 0 && len(l.buckets) >= l.maxBuckets {
+		l.remove(l.recent.Back())
+	}
+
+	b := &bucket{key: key, tokens: l.burst, last: now}
+	l.buckets[key] = l.recent.PushFront(b)
+
+	return b
+}
+
+func (l *Limiter) remove(e *list.Element) {
+	l.recent.Remove(e)
+	delete(l.buckets, e.Value.(*bucket).key) //nolint:forcetypeassert // Always a bucket
+}
+
+// Len returns the number of buckets being tracked.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+// Cleanup removes the buckets that have been idle long enough to be full
+// again, since they behave just like new ones.
+func (l *Limiter) Cleanup() {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, e := range l.buckets {
+		b := e.Value.(*bucket) //nolint:forcetypeassert // Always a bucket
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			l.remove(e)
+		}
+	}
+}
+
+// Run removes stale buckets every interval until the context is done.
+func (l *Limiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Cleanup()
+		}
+	}
+}
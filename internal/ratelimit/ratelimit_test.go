@@ -0,0 +1,142 @@
+package ratelimit_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/ratelimit"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	l := ratelimit.New(2, 3, ratelimit.WithClock(clock.Now))
+
+	// The burst is allowed right away
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("expected request over the burst to be denied")
+	}
+
+	if wait != 500*time.Millisecond {
+		t.Errorf("expected to wait %v, got %v", 500*time.Millisecond, wait)
+	}
+
+	// Other keys have their own bucket
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("expected another key to be allowed")
+	}
+
+	// Tokens are refilled at the given rate
+	clock.Advance(500 * time.Millisecond)
+
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected request to be allowed after the refill")
+	}
+
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("expected request to be denied before the next refill")
+	}
+
+	// Buckets never hold more than the burst
+	clock.Advance(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("expected request over the burst to be denied")
+	}
+}
+
+func TestLimiter_Cleanup(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	l := ratelimit.New(1, 2, ratelimit.WithClock(clock.Now))
+
+	l.Allow("a")
+	l.Allow("a")
+	l.Allow("b")
+
+	// Buckets that are not full yet are kept
+	clock.Advance(time.Second)
+	l.Cleanup()
+
+	if got := l.Len(); got != 1 {
+		t.Fatalf("expected 1 bucket, got %d", got)
+	}
+
+	// Until they are full again
+	clock.Advance(time.Second)
+	l.Cleanup()
+
+	if got := l.Len(); got != 0 {
+		t.Fatalf("expected 0 buckets, got %d", got)
+	}
+}
+
+func TestLimiter_MaxBuckets(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	l := ratelimit.New(1, 1, ratelimit.WithClock(clock.Now), ratelimit.WithMaxBuckets(2))
+
+	l.Allow("a")
+	l.Allow("b")
+
+	// Using a bucket makes it the most recent
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("expected request over the burst to be denied")
+	}
+
+	// New keys over the cap drop the least recently used bucket
+	l.Allow("c")
+
+	if got := l.Len(); got != 2 {
+		t.Fatalf("expected 2 buckets, got %d", got)
+	}
+
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("expected the bucket of a to be kept")
+	}
+
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("expected the bucket of b to be dropped")
+	}
+}
@@ -1,13 +1,182 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/store"
 )
 
-func HealthCheckHandler(_ *config.Config) http.Handler {
+// Reload statuses reported by the health endpoints.
+const (
+	ReloadStatusNone    = "none"
+	ReloadStatusSuccess = "success"
+	ReloadStatusFailure = "failure"
+)
+
+// HealthStatus is the detailed state of the server.
+type HealthStatus struct {
+	Status        string       `json:"status"`
+	Reason        string       `json:"reason,omitempty"`
+	Version       string       `json:"version"`
+	Uptime        string       `json:"uptime"`
+	UptimeSeconds int64        `json:"uptimeSeconds"`
+	Resources     int          `json:"resources"`
+	Source        HealthSource `json:"source"`
+	LoadedAt      time.Time    `json:"loadedAt"`
+	LastReload    HealthReload `json:"lastReload"`
+}
+
+// HealthSource are the files the webfingers are loaded from.
+type HealthSource struct {
+	Fingers string `json:"fingers"`
+	URNs    string `json:"urns"`
+}
+
+// HealthReload is the outcome of the last reload.
+type HealthReload struct {
+	Status string     `json:"status"`
+	At     *time.Time `json:"at,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// Health reports whether the server is alive and ready to serve.
+type Health struct {
+	cfg     *config.Config
+	store   *store.Store
+	started time.Time
+
+	mu            sync.RWMutex
+	lastReloadAt  time.Time
+	lastReloadErr error
+}
+
+// NewHealth tracks the health of the server serving the webfingers in
+// the store.
+func NewHealth(cfg *config.Config, s *store.Store) *Health {
+	h := &Health{
+		cfg:     cfg,
+		store:   s,
+		started: time.Now(),
+	}
+
+	s.OnReload(func(err error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.lastReloadAt = time.Now()
+		h.lastReloadErr = err
+	})
+
+	return h
+}
+
+// Status returns the detailed state of the server.
+func (h *Health) Status() *HealthStatus {
+	now := time.Now()
+	snap := h.store.Current()
+	uptime := now.Sub(h.started)
+
+	status := &HealthStatus{
+		Status:        "ready",
+		Version:       h.cfg.Version,
+		Uptime:        uptime.Truncate(time.Second).String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Resources:     len(snap.Fingers),
+		Source: HealthSource{
+			Fingers: h.cfg.FingerPath,
+			URNs:    h.cfg.URNPath,
+		},
+		LoadedAt:   snap.LoadedAt,
+		LastReload: HealthReload{Status: ReloadStatusNone},
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.lastReloadAt.IsZero() {
+		at := h.lastReloadAt
+		status.LastReload.At = &at
+		status.LastReload.Status = ReloadStatusSuccess
+
+		if h.lastReloadErr != nil {
+			status.LastReload.Status = ReloadStatusFailure
+			status.LastReload.Error = h.lastReloadErr.Error()
+		}
+	}
+
+	switch {
+	case h.lastReloadErr != nil:
+		status.Status = "not ready"
+		status.Reason = "the last reload failed"
+	case h.cfg.ReadyMaxAge > 0 && now.Sub(snap.LoadedAt) > h.cfg.ReadyMaxAge:
+		status.Status = "not ready"
+		status.Reason = "the webfingers are stale"
+	}
+
+	return status
+}
+
+// LivenessHandler reports that the server is running.
+func (h *Health) LivenessHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wantsDetails(r) {
+			status := h.Status()
+
+			// Liveness doesn't depend on the data
+			status.Status = "ok"
+			status.Reason = ""
+
+			writeHealth(w, http.StatusOK, status)
+
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
 	})
 }
+
+// ReadinessHandler reports whether the server is ready to serve, which it
+// isn't if the webfingers are stale or the last reload failed.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := h.Status()
+
+		code := http.StatusOK
+		if status.Reason != "" {
+			code = http.StatusServiceUnavailable
+		}
+
+		if wantsDetails(r) {
+			writeHealth(w, code, status)
+
+			return
+		}
+
+		if code != http.StatusOK {
+			http.Error(w, status.Status+": "+status.Reason, code)
+
+			return
+		}
+
+		w.WriteHeader(code)
+	})
+}
+
+// wantsDetails reports whether the client asked for the JSON status, with
+// the verbose query param or by accepting JSON.
+func wantsDetails(r *http.Request) bool {
+	return r.URL.Query().Has("verbose") || strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func writeHealth(w http.ResponseWriter, code int, status *HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(status)
+}
@@ -2,17 +2,22 @@ package server_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"git.maronato.dev/maronato/finger/internal/config"
 	"git.maronato.dev/maronato/finger/internal/log"
 	"git.maronato.dev/maronato/finger/internal/server"
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/webfingers"
 )
 
-func TestHealthcheckHandler(t *testing.T) {
+func TestHealth_LivenessHandler(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
@@ -28,7 +33,7 @@ func TestHealthcheckHandler(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// Create a new handler
-	h := server.HealthCheckHandler(cfg)
+	h := server.NewHealth(cfg, store.New(nil, nil)).LivenessHandler()
 
 	// Serve the request
 	h.ServeHTTP(rec, req)
@@ -38,3 +43,112 @@ func TestHealthcheckHandler(t *testing.T) {
 		t.Errorf("expected status code %d, got %d", http.StatusOK, rec.Code)
 	}
 }
+
+func TestHealth_ReadinessHandler(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {Subject: "acct:user@example.com"},
+	}
+
+	get := func(h http.Handler, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+
+		return rec
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.NewConfig()
+		cfg.Version = "1.2.3"
+
+		h := server.NewHealth(cfg, store.New(fingers, nil)).ReadinessHandler()
+
+		if rec := get(h, "/readyz"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		rec := get(h, "/readyz?verbose")
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected JSON, got %s", ct)
+		}
+
+		var status server.HealthStatus
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatalf("error decoding status: %v", err)
+		}
+
+		if status.Status != "ready" || status.Version != "1.2.3" || status.Resources != 1 {
+			t.Errorf("unexpected status %+v", status)
+		}
+
+		if status.Source.Fingers != cfg.FingerPath || status.Source.URNs != cfg.URNPath {
+			t.Errorf("unexpected source %+v", status.Source)
+		}
+
+		if status.LastReload.Status != server.ReloadStatusNone {
+			t.Errorf("expected no reload, got %+v", status.LastReload)
+		}
+	})
+
+	t.Run("last reload failed", func(t *testing.T) {
+		t.Parallel()
+
+		fail := true
+		s := store.New(fingers, func(_ context.Context) (webfingers.WebFingers, error) {
+			if fail {
+				return nil, errors.New("invalid fingers file")
+			}
+
+			return fingers, nil
+		})
+
+		h := server.NewHealth(config.NewConfig(), s).ReadinessHandler()
+
+		_ = s.Reload(context.Background())
+
+		rec := get(h, "/readyz?verbose")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status code %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+
+		var status server.HealthStatus
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatalf("error decoding status: %v", err)
+		}
+
+		if status.LastReload.Status != server.ReloadStatusFailure || !strings.Contains(status.LastReload.Error, "invalid fingers file") {
+			t.Errorf("expected a failed reload, got %+v", status.LastReload)
+		}
+
+		// A successful reload makes it ready again
+		fail = false
+		_ = s.Reload(context.Background())
+
+		if rec := get(h, "/readyz"); rec.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.NewConfig()
+		cfg.ReadyMaxAge = time.Millisecond * 10
+
+		h := server.NewHealth(cfg, store.New(fingers, nil)).ReadinessHandler()
+
+		time.Sleep(time.Millisecond * 20)
+
+		rec := get(h, "/readyz")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status code %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+
+		if !strings.Contains(rec.Body.String(), "stale") {
+			t.Errorf("expected the reason in the body, got %q", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/systemd"
+)
+
+// Listen creates the listeners for the main server and, if configured,
+// the HTTP redirect server.
+//
+// Sockets passed by systemd socket activation take precedence over the
+// config: the first one is used by the main server and the second one,
+// if any, by the redirect server.
+func Listen(cfg *config.Config) (main, redirect net.Listener, err error) {
+	activated, err := systemd.Listeners()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting systemd sockets: %w", err)
+	}
+
+	if len(activated) > 0 {
+		main = activated[0]
+
+		if len(activated) > 1 {
+			redirect = activated[1]
+		}
+
+		return main, redirect, nil
+	}
+
+	if cfg.IsUnixSocket() {
+		main, err = listenUnix(cfg)
+	} else {
+		main, err = net.Listen("tcp", cfg.GetAddr())
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listening on %s: %w", cfg.GetAddr(), err)
+	}
+
+	if cfg.HTTPRedirectPort != "" {
+		redirect, err = net.Listen("tcp", cfg.GetRedirectAddr())
+		if err != nil {
+			main.Close()
+
+			return nil, nil, fmt.Errorf("error listening on %s: %w", cfg.GetRedirectAddr(), err)
+		}
+	}
+
+	return main, redirect, nil
+}
+
+// listenUnix listens on the Unix socket in the config, replacing any
+// socket left behind by a previous run.
+func listenUnix(cfg *config.Config) (net.Listener, error) {
+	path := cfg.GetSocketPath()
+
+	mode, err := cfg.GetSocketMode()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already a config error
+	}
+
+	// Remove stale sockets, but never other kinds of files
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%w: %s exists and is not a socket", os.ErrExist, path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("error removing stale socket: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error checking socket: %w", err)
+	}
+
+	// Create the socket with the mode, since other users could connect
+	// before the chmod otherwise
+	var l net.Listener
+
+	err = withUmask(mode, func() error {
+		l, err = net.Listen("unix", path)
+
+		return err //nolint:wrapcheck // Wrapped by the caller
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapped by the caller
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+
+		return nil, fmt.Errorf("error setting socket permissions: %w", err)
+	}
+
+	return l, nil
+}
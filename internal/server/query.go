@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+// QueryResult is the response the server gives to a webfinger request.
+type QueryResult struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Query looks up the resource and rels in the webfingers offline, going
+// through the same handler as requests to the server.
+func Query(ctx context.Context, cfg *config.Config, fingers webfingers.WebFingers, resource string, rels []string) (*QueryResult, error) {
+	h, err := WebfingerHandler(cfg, store.New(fingers, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{"resource": {resource}}
+	if len(rels) > 0 {
+		q["rel"] = rels
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?"+q.Encode(), http.NoBody).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	return &QueryResult{
+		Status: w.Code,
+		Header: w.Header(),
+		Body:   w.Body.Bytes(),
+	}, nil
+}
+
+// ExplainQuery returns why the resource wasn't found in the webfingers, or
+// why some of the rels didn't match any of its links.
+func ExplainQuery(fingers webfingers.WebFingers, urnAliases webfingers.URNAliases, resource string, rels []string) []string {
+	finger, ok := fingers[resource]
+	if !ok {
+		return explainMiss(fingers, resource)
+	}
+
+	var notes []string
+
+	served := make(map[string]bool, len(finger.Links))
+	for _, l := range finger.Links {
+		served[l.Rel] = true
+	}
+
+	for _, rel := range rels {
+		if served[rel] {
+			continue
+		}
+
+		_, isProperty := finger.Properties[rel]
+		urn, isAlias := urnAliases[rel]
+
+		switch {
+		case isProperty:
+			notes = append(notes, fmt.Sprintf("%s is a property of %s. Properties are always returned and can't be picked with rel", rel, resource))
+		case isAlias:
+			notes = append(notes, fmt.Sprintf("no link of %s has the rel %s. It's a URN alias, so its links are served with the rel %s", resource, rel, urn))
+		default:
+			notes = append(notes, fmt.Sprintf("no link of %s has the rel %s", resource, rel))
+		}
+	}
+
+	return notes
+}
+
+func explainMiss(fingers webfingers.WebFingers, resource string) []string {
+	subject, err := webfingers.ParseSubject(resource)
+	if err != nil {
+		return []string{fmt.Sprintf("%s is not a valid resource: it must be an acct: URI, an email address or a URL", resource)}
+	}
+
+	// Lookups must use the subject exactly
+	if _, ok := fingers[subject]; ok {
+		return []string{fmt.Sprintf("%s is not the subject of the resource. Clients must look up %s", resource, subject)}
+	}
+
+	var similar []string
+
+	for s := range fingers {
+		if strings.EqualFold(s, subject) {
+			similar = append(similar, s)
+		}
+	}
+
+	if len(similar) > 0 {
+		sort.Strings(similar)
+
+		return []string{fmt.Sprintf("no resource has the subject %s. Lookups are case sensitive, did you mean %s?", subject, strings.Join(similar, ", "))}
+	}
+
+	return []string{fmt.Sprintf("no resource has the subject %s", subject)}
+}
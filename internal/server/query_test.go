@@ -0,0 +1,137 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/server"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+func queryFingers() webfingers.WebFingers {
+	return webfingers.WebFingers{
+		"acct:alice@example.com": {
+			Subject:    "acct:alice@example.com",
+			Properties: map[string]string{"name": "Alice"},
+			Links: []webfingers.Link{
+				{Rel: "http://webfinger.net/rel/avatar", Href: "https://example.com/alice.png"},
+				{Rel: "http://webfinger.net/rel/profile-page", Href: "https://alice.example.com"},
+			},
+		},
+		"acct:Bob@example.com": {Subject: "acct:Bob@example.com"},
+	}
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewConfig()
+	fingers := queryFingers()
+
+	res, err := server.Query(context.Background(), cfg, fingers, "acct:alice@example.com", []string{"http://webfinger.net/rel/avatar"})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	if res.Status != http.StatusOK || res.Header.Get("Content-Type") != "application/jrd+json" {
+		t.Fatalf("unexpected response %d %v", res.Status, res.Header)
+	}
+
+	var got webfingers.WebFinger
+	if err := json.Unmarshal(res.Body, &got); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+
+	// The rels filter the links like they do on the server
+	want := webfingers.WebFinger{
+		Subject:    "acct:alice@example.com",
+		Properties: map[string]string{"name": "Alice"},
+		Links:      fingers["acct:alice@example.com"].Links[:1],
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Query() = %+v, want %+v", got, want)
+	}
+
+	res, err = server.Query(context.Background(), cfg, fingers, "alice@example.com", nil)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	if res.Status != http.StatusNotFound {
+		t.Errorf("expected status code %d, got %d", http.StatusNotFound, res.Status)
+	}
+}
+
+func TestExplainQuery(t *testing.T) {
+	t.Parallel()
+
+	urnAliases := webfingers.URNAliases{"avatar": "http://webfinger.net/rel/avatar"}
+
+	tests := []struct {
+		name     string
+		resource string
+		rels     []string
+		want     []string
+	}{
+		{
+			name:     "hit",
+			resource: "acct:alice@example.com",
+			rels:     []string{"http://webfinger.net/rel/avatar"},
+			want:     nil,
+		},
+		{
+			name:     "not the subject",
+			resource: "alice@example.com",
+			want:     []string{"Clients must look up acct:alice@example.com"},
+		},
+		{
+			name:     "different case",
+			resource: "acct:bob@example.com",
+			want:     []string{"did you mean acct:Bob@example.com?"},
+		},
+		{
+			name:     "invalid resource",
+			resource: "not a resource",
+			want:     []string{"is not a valid resource"},
+		},
+		{
+			name:     "missing resource",
+			resource: "acct:carol@example.com",
+			want:     []string{"no resource has the subject acct:carol@example.com"},
+		},
+		{
+			name:     "unmatched rels",
+			resource: "acct:alice@example.com",
+			rels:     []string{"avatar", "name", "http://example.com/rel/blog"},
+			want: []string{
+				"served with the rel http://webfinger.net/rel/avatar",
+				"name is a property",
+				"has the rel http://example.com/rel/blog",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := server.ExplainQuery(queryFingers(), urnAliases, tc.resource, tc.rels)
+			if len(got) != len(tc.want) {
+				t.Fatalf("ExplainQuery() = %q, want %d notes", got, len(tc.want))
+			}
+
+			for i, note := range got {
+				if !strings.Contains(note, tc.want[i]) {
+					t.Errorf("ExplainQuery() note %d = %q, want it to contain %q", i, note, tc.want[i])
+				}
+			}
+		})
+	}
+}
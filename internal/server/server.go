@@ -2,97 +2,561 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"git.maronato.dev/maronato/finger/handler"
+	"git.maronato.dev/maronato/finger/internal/admin"
+	"git.maronato.dev/maronato/finger/internal/analytics"
+	"git.maronato.dev/maronato/finger/internal/audit"
+	"git.maronato.dev/maronato/finger/internal/ban"
 	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/fingerreader"
 	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/internal/metrics"
 	"git.maronato.dev/maronato/finger/internal/middleware"
+	"git.maronato.dev/maronato/finger/internal/ratelimit"
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/internal/systemd"
 	"git.maronato.dev/maronato/finger/webfingers"
 	"golang.org/x/sync/errgroup"
 )
 
-const (
-	// ReadTimeout is the maximum duration for reading the entire
-	// request, including the body.
-	ReadTimeout = 5 * time.Second
-	// WriteTimeout is the maximum duration before timing out
-	// writes of the response.
-	WriteTimeout = 10 * time.Second
-	// IdleTimeout is the maximum amount of time to wait for the
-	// next request when keep-alives are enabled.
-	IdleTimeout = 30 * time.Second
-	// ReadHeaderTimeout is the amount of time allowed to read
-	// request headers.
-	ReadHeaderTimeout = 2 * time.Second
-	// RequestTimeout is the maximum duration for the entire
-	// request.
-	RequestTimeout = 7 * 24 * time.Hour
-)
-
 func StartServer(ctx context.Context, cfg *config.Config, fingers webfingers.WebFingers) error {
-	l := log.FromContext(ctx)
+	load := func(ctx context.Context) (webfingers.WebFingers, error) {
+		return fingerreader.Load(ctx, cfg) //nolint:wrapcheck // Wrapped by the store
+	}
+
+	// Manage the webfingers through the admin API if enabled
+	var (
+		api    *admin.API
+		tokens []admin.Token
+	)
+
+	if cfg.AdminAPIEnabled() {
+		var err error
+
+		tokens, err = admin.ReadTokens(cfg.AdminTokensFile)
+		if err != nil {
+			return fmt.Errorf("error reading admin tokens: %w", err)
+		}
+
+		var apiOpts []admin.Option
+
+		source := fingerreader.NewSource(cfg)
+
+		// Save changes to the files, keeping their comments
+		if cfg.AdminPersist {
+			apiOpts = append(apiOpts, admin.WithSave(source.Save))
+		}
+
+		api = admin.New(source.Load, apiOpts...)
+
+		// The API keeps its own copy of the files in sync
+		load = api.Load
+
+		if fingers, err = api.Load(ctx); err != nil {
+			return fmt.Errorf("error loading finger files: %w", err)
+		}
+	}
+
+	// Serve the webfingers from a store so they can be reloaded
+	s := store.New(fingers, load)
+
+	// Record every change to the webfingers if enabled
+	if cfg.AuditFile != "" {
+		stopRecording := recordChanges(ctx, s, audit.New(cfg.AuditFile))
+		defer stopRecording()
+	}
+
+	var handlerOpts []handler.Option
+
+	// Collect metrics if enabled
+	var m *metrics.Metrics
+
+	if cfg.Metrics {
+		m = metrics.New()
+		m.CollectStore(s)
+
+		handlerOpts = append(handlerOpts, handler.WithObserver(func(_ *http.Request, lookup handler.Lookup) {
+			m.ObserveLookup(string(lookup.Outcome))
+		}))
+	}
+
+	// Lookups are hidden the same way in the logs and the analytics
+	redactor := newLookupRedactor(cfg)
+
+	// Count the top looked up resources if enabled
+	var stats *analytics.Analytics
+
+	if cfg.Analytics {
+		statsOpts := []analytics.Option{analytics.WithRedactor(redactor)}
+		if cfg.AnalyticsFile != "" {
+			statsOpts = append(statsOpts, analytics.WithClosedWindows())
+		}
+
+		stats = analytics.New(cfg.AnalyticsTop, cfg.AnalyticsWindow, statsOpts...)
+
+		handlerOpts = append(handlerOpts, handler.WithObserver(func(_ *http.Request, lookup handler.Lookup) {
+			stats.Observe(lookup.Resource, lookup.Outcome == handler.OutcomeHit)
+		}))
+	}
+
+	webfingerHandler, err := newWebfingerHandler(cfg, s, redactor, handlerOpts...)
+	if err != nil {
+		return err
+	}
+
+	// Rate limit the webfinger endpoint if enabled
+	var limiter *ratelimit.Limiter
+
+	if cfg.RateLimitEnabled() {
+		allowlist, err := cfg.GetRateLimitAllowlist()
+		if err != nil {
+			return err //nolint:wrapcheck // Already a config error
+		}
+
+		limiter = ratelimit.New(cfg.RateLimit, cfg.RateLimitBurst)
+		webfingerHandler = middleware.RateLimit(limiter, allowlist)(webfingerHandler)
+	}
+
+	// Ban clients that look up too many missing resources if enabled
+	var tracker *ban.Tracker
+
+	if cfg.BanEnabled() {
+		l := log.FromContext(ctx)
+
+		tracker = ban.New(cfg.BanThreshold, cfg.BanWindow, cfg.BanDuration, ban.WithOnBan(func(key string, until time.Time) {
+			l.Warn("Banned client for too many misses", slog.String("remote", key), slog.Time("until", until))
+
+			if m != nil {
+				m.ObserveBan()
+			}
+		}))
+		webfingerHandler = middleware.Ban(tracker, cfg.BanTarpit)(webfingerHandler)
+	}
+
+	// Report the health of the server
+	health := NewHealth(cfg, s)
 
 	// Create the server mux
 	mux := http.NewServeMux()
-	mux.Handle("/.well-known/webfinger", handler.WebfingerHandler(fingers))
-	mux.Handle("/healthz", HealthCheckHandler(cfg))
+	mux.Handle("/.well-known/webfinger", webfingerHandler)
+	mux.Handle("/healthz", health.LivenessHandler())
+	mux.Handle("/readyz", health.ReadinessHandler())
 
-	// Create a new server
-	srv := &http.Server{
-		Addr: cfg.GetAddr(),
-		Handler: middleware.RequestLogger(
-			middleware.Recoverer(
-				http.TimeoutHandler(mux, RequestTimeout, "request timed out"),
-			),
-		),
-		ReadHeaderTimeout: ReadHeaderTimeout,
-		ReadTimeout:       ReadTimeout,
-		WriteTimeout:      WriteTimeout,
-		IdleTimeout:       IdleTimeout,
+	// Admin endpoints are served on their own listener if configured
+	adminMux := mux
+	if cfg.AdminAddr != "" {
+		adminMux = http.NewServeMux()
+		adminMux.Handle("/healthz", health.LivenessHandler())
+		adminMux.Handle("/readyz", health.ReadinessHandler())
+
+		// Metrics and analytics are never public
+		if m != nil {
+			adminMux.Handle("/metrics", m.Registry.Handler())
+		}
+
+		if stats != nil {
+			adminMux.Handle("/analytics", stats.Handler())
+		}
 	}
 
+	if api != nil {
+		apiHandler := api.Handler(s, tokens)
+		adminMux.Handle("/admin/resources", apiHandler)
+		adminMux.Handle("/admin/urns", apiHandler)
+	}
+
+	// Create the middleware chain
+	var h http.Handler = mux
+	if cfg.RequestTimeout > 0 {
+		h = http.TimeoutHandler(h, cfg.RequestTimeout, "request timed out")
+	}
+
+	h = middleware.Recoverer(h)
+	if cfg.Compress {
+		h = middleware.Compress(cfg.CompressMinSize)(h)
+	}
+
+	if m != nil {
+		h = middleware.Metrics(m)(h)
+	}
+
+	h = middleware.RequestLogger(h)
+
+	// Find the real client IP behind trusted proxies
+	trusted, err := cfg.GetTrustedProxies()
+	if err != nil {
+		return err //nolint:wrapcheck // Already a config error
+	}
+
+	if len(trusted) > 0 || cfg.TrustUnixSocket {
+		header, err := cfg.GetClientIPHeader()
+		if err != nil {
+			return err //nolint:wrapcheck // Already a config error
+		}
+
+		h = middleware.RealIP(trusted, header, cfg.TrustUnixSocket)(h)
+	}
+
+	// Tie every log line to the request and its trace
+	h = middleware.Trace(h)
+
+	// Create the listeners
+	ln, redirectLn, err := Listen(cfg)
+	if err != nil {
+		return err
+	}
+
+	var adminLn net.Listener
+
+	if cfg.AdminAddr != "" {
+		adminLn, err = net.Listen("tcp", cfg.AdminAddr)
+		if err != nil {
+			closeListeners(ln, redirectLn)
+
+			return fmt.Errorf("error listening on %s: %w", cfg.AdminAddr, err)
+		}
+	}
+
+	// Create a new server
+	srv := newHTTPServer(cfg, ln, h)
+
 	// Create the errorgroup that will manage the server execution
 	eg, egCtx := errgroup.WithContext(ctx)
 
+	// Serve HTTPS if a certificate was provided
+	serve := func() error {
+		return srv.Serve(ln)
+	}
+
+	if cfg.TLSEnabled() {
+		reloader, err := NewCertReloader(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			closeListeners(ln, redirectLn, adminLn)
+
+			return fmt.Errorf("error loading TLS certificate: %w", err)
+		}
+
+		srv.TLSConfig = &tls.Config{
+			GetCertificate: reloader.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+
+		serve = func() error {
+			return srv.ServeTLS(ln, "", "")
+		}
+
+		// Reload the certificate when it changes
+		eg.Go(func() error {
+			reloader.Watch(egCtx, CertReloadInterval)
+
+			return nil
+		})
+	}
+
+	runServer(egCtx, eg, srv, cfg.ShutdownTimeout, serve)
+
+	// Serve the admin endpoints
+	if adminLn != nil {
+		adminSrv := newHTTPServer(cfg, adminLn, middleware.Trace(middleware.RequestLogger(middleware.Recoverer(adminMux))))
+
+		runServer(egCtx, eg, adminSrv, cfg.ShutdownTimeout, func() error {
+			return adminSrv.Serve(adminLn)
+		})
+	}
+
+	// Redirect plain HTTP requests to HTTPS
+	if redirectLn != nil {
+		redirectSrv := newHTTPServer(cfg, redirectLn, RedirectHandler(cfg.Port))
+
+		runServer(egCtx, eg, redirectSrv, cfg.ShutdownTimeout, func() error {
+			return redirectSrv.Serve(redirectLn)
+		})
+	}
+
+	// Remove stale rate limit buckets
+	if limiter != nil {
+		eg.Go(func() error {
+			limiter.Run(egCtx, ratelimit.CleanupInterval)
+
+			return nil
+		})
+	}
+
+	// Forget clients with no recent misses
+	if tracker != nil {
+		eg.Go(func() error {
+			tracker.Run(egCtx, ban.CleanupInterval)
+
+			return nil
+		})
+	}
+
+	// Write the analytics to their file
+	if stats != nil && cfg.AnalyticsFile != "" {
+		eg.Go(func() error {
+			stats.RunFlush(egCtx, cfg.AnalyticsFile, cfg.AnalyticsFlushInterval)
+
+			return nil
+		})
+	}
+
+	// Reload the webfingers on SIGHUP
+	eg.Go(func() error {
+		watchReloads(egCtx, s)
+
+		return nil
+	})
+
+	// Let systemd know the server is up, and keep its watchdog happy
+	notifySystemd(egCtx, systemd.Ready)
+
+	eg.Go(func() error {
+		runWatchdog(egCtx)
+
+		return nil
+	})
+	eg.Go(func() error {
+		<-egCtx.Done()
+		notifySystemd(egCtx, systemd.Stopping)
+
+		return nil
+	})
+
+	// Wait for the server to exit and check for errors that
+	// are not caused by the context being canceled.
+	if err := eg.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("server exited with error: %w", err)
+	}
+
+	return nil
+}
+
+// newHTTPServer creates a server for the handler on the listener, with the
+// configured timeouts.
+func newHTTPServer(cfg *config.Config, ln net.Listener, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ln.Addr().String(),
+		Handler:           h,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// runServer starts the server in the errgroup and gracefully shuts it
+// down when the context is done. Connections still open after the
+// shutdown timeout are closed, unless it's 0.
+func runServer(ctx context.Context, eg *errgroup.Group, srv *http.Server, shutdownTimeout time.Duration, serve func() error) {
+	l := log.FromContext(ctx)
+
 	// Start the server
 	eg.Go(func() error {
 		l.Info("Starting server", slog.String("addr", srv.Addr))
 
 		// Use the global context for the server
 		srv.BaseContext = func(_ net.Listener) context.Context {
-			return egCtx
+			return ctx
 		}
 
-		return srv.ListenAndServe() //nolint:wrapcheck // We wrap the error in the errgroup
+		return serve()
 	})
 	// Gracefully shutdown the server when the context is done
 	eg.Go(func() error {
 		// Wait for the context to be done
-		<-egCtx.Done()
+		<-ctx.Done()
+
+		l.Info("Shutting down server", slog.String("addr", srv.Addr))
 
-		l.Info("Shutting down server")
 		// Disable the cancel since we don't wan't to force
 		// the server to shutdown if the context is canceled.
-		noCancelCtx := context.WithoutCancel(egCtx)
+		shutdownCtx := context.WithoutCancel(ctx)
+
+		// But don't wait for stuck connections forever
+		if shutdownTimeout > 0 {
+			var cancel context.CancelFunc
+
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, shutdownTimeout)
+			defer cancel()
+		}
+
+		err := srv.Shutdown(shutdownCtx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			l.Warn("Shutdown timed out, closing remaining connections", slog.String("addr", srv.Addr))
 
-		return srv.Shutdown(noCancelCtx) //nolint:wrapcheck // We wrap the error in the errgroup
+			return srv.Close() //nolint:wrapcheck // We wrap the error in the errgroup
+		}
+
+		return err //nolint:wrapcheck // We wrap the error in the errgroup
 	})
 
 	// Log when the server is fully shutdown
 	srv.RegisterOnShutdown(func() {
-		l.Info("Server shutdown complete")
+		l.Info("Server shutdown complete", slog.String("addr", srv.Addr))
 	})
+}
 
-	// Wait for the server to exit and check for errors that
-	// are not caused by the context being canceled.
-	if err := eg.Wait(); err != nil && ctx.Err() == nil {
-		return fmt.Errorf("server exited with error: %w", err)
+// closeListeners closes every non-nil listener.
+func closeListeners(listeners ...net.Listener) {
+	for _, l := range listeners {
+		if l != nil {
+			l.Close()
+		}
 	}
+}
 
-	return nil
+// watchReloads reloads the webfingers every time the process receives
+// a SIGHUP, until the context is done.
+func watchReloads(ctx context.Context, s *store.Store) {
+	l := log.FromContext(ctx)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			l.Info("Reloading webfingers")
+			notifySystemd(ctx, systemd.Reloading())
+
+			if err := s.Reload(audit.WithAction(ctx, audit.ActionReload)); err != nil {
+				// Keep serving the previous webfingers
+				l.Error("Failed to reload webfingers", slog.Any("error", err))
+			} else {
+				l.Info(fmt.Sprintf("Loaded %d webfingers", len(s.Current().Fingers)))
+			}
+
+			notifySystemd(ctx, systemd.Ready)
+		}
+	}
+}
+
+// auditQueueSize is how many audit events can wait to be written before
+// changes to the webfingers wait for them.
+const auditQueueSize = 64
+
+// recordChanges writes an audit event for every change to the webfingers
+// in the store and every failed reload. Events are written in the
+// background, so slow disks don't hold up changes. The returned function
+// writes the pending events and stops.
+func recordChanges(ctx context.Context, s *store.Store, auditLog *audit.Log) func() {
+	l := log.FromContext(ctx)
+
+	write := func(e audit.Event) {
+		if err := auditLog.Write(e); err != nil {
+			l.Error("Failed to write audit event", slog.Any("error", err))
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		stopped bool
+		events  = make(chan audit.Event, auditQueueSize)
+		done    = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+
+		for e := range events {
+			write(e)
+		}
+	}()
+
+	record := func(e audit.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Changes made while shutting down are written right away
+		if stopped {
+			write(e)
+
+			return
+		}
+
+		events <- e
+	}
+
+	s.OnChange(func(ctx context.Context, prev, next *store.Snapshot) {
+		e := audit.NewEvent(ctx, prev.Fingers, next.Fingers)
+		e.Actor, _ = admin.ActorFromContext(ctx)
+
+		// Reloads of the same webfingers aren't changes
+		if e.Empty() {
+			return
+		}
+
+		record(e)
+	})
+
+	// Failed reloads don't change the webfingers, but are recorded too
+	s.OnReload(func(err error) {
+		if err != nil {
+			record(audit.Event{
+				Time:   time.Now().UTC(),
+				Action: audit.ActionReload,
+				Error:  err.Error(),
+			})
+		}
+	})
+
+	return func() {
+		mu.Lock()
+		stopped = true
+		close(events)
+		mu.Unlock()
+
+		<-done
+	}
+}
+
+// runWatchdog pings the systemd watchdog until the context is done.
+func runWatchdog(ctx context.Context) {
+	l := log.FromContext(ctx)
+
+	interval, err := systemd.WatchdogInterval()
+	if err != nil {
+		l.Error("Invalid systemd watchdog settings", slog.Any("error", err))
+
+		return
+	}
+
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			notifySystemd(ctx, systemd.Watchdog)
+		}
+	}
+}
+
+// notifySystemd sends a state message to systemd, logging any failures.
+func notifySystemd(ctx context.Context, state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		log.FromContext(ctx).Error("Failed to notify systemd", slog.String("state", state), slog.Any("error", err))
+	}
 }
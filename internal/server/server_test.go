@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"git.maronato.dev/maronato/finger/internal/audit"
 	"git.maronato.dev/maronato/finger/internal/config"
 	"git.maronato.dev/maronato/finger/internal/log"
 	"git.maronato.dev/maronato/finger/internal/server"
@@ -204,3 +209,307 @@ func TestStartServer(t *testing.T) {
 		}
 	})
 }
+
+func TestStartServer_UnixSocket(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+
+	cfg := config.NewConfig()
+	l := log.NewLogger(&strings.Builder{}, cfg)
+
+	ctx = log.WithLogger(ctx, l)
+
+	socket := filepath.Join(t.TempDir(), "finger.sock")
+	cfg.Host = config.UnixSocketPrefix + socket
+	cfg.SocketMode = "0600"
+
+	go func() {
+		// Start the server
+		err := server.StartServer(ctx, cfg, nil)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}()
+
+	// Wait for the server to start
+	time.Sleep(time.Millisecond * 50)
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("expected socket to exist, got %v", err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected socket mode %v, got %v", os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	// Create a client that connects to the socket
+	c := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/healthz", http.NoBody)
+
+	resp, err := c.Do(r)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestStartServer_Metrics(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+	defer cancel()
+
+	cfg := config.NewConfig()
+	l := log.NewLogger(&strings.Builder{}, cfg)
+
+	ctx = log.WithLogger(ctx, l)
+
+	// Use new ports
+	cfg.Port = "8200"
+	cfg.AdminAddr = "localhost:8201"
+	cfg.Metrics = true
+	cfg.Analytics = true
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {Subject: "acct:user@example.com"},
+	}
+
+	go func() {
+		// Start the server
+		err := server.StartServer(ctx, cfg, fingers)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}()
+
+	// Wait for the server to start
+	time.Sleep(time.Millisecond * 50)
+
+	get := func(url string) (int, string) {
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		return resp.StatusCode, string(body)
+	}
+
+	get("http://" + cfg.GetAddr() + "/.well-known/webfinger?resource=acct:user@example.com")
+	get("http://" + cfg.GetAddr() + "/.well-known/webfinger?resource=acct:other@example.com")
+
+	// Metrics are not served on the main listener
+	if code, _ := get("http://" + cfg.GetAddr() + "/metrics"); code != http.StatusNotFound {
+		t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+	}
+
+	code, body := get("http://" + cfg.AdminAddr + "/metrics")
+	if code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+	}
+
+	for _, want := range []string{
+		`finger_http_requests_total{method="GET",status="200"} 1`,
+		`finger_http_requests_total{method="GET",status="404"} 2`,
+		`finger_webfinger_lookups_total{result="hit"} 1`,
+		`finger_webfinger_lookups_total{result="miss"} 1`,
+		"finger_resources 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+
+	// Analytics are served on the admin listener too
+	code, body = get("http://" + cfg.AdminAddr + "/analytics")
+	if code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+	}
+
+	for _, want := range []string{
+		`"hits":[{"key":"acct:user@example.com","count":1,"error":0}]`,
+		`"misses":[{"key":"acct:other@example.com","count":1,"error":0}]`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestStartServer_ShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := config.NewConfig()
+	l := log.NewLogger(&strings.Builder{}, cfg)
+
+	ctx = log.WithLogger(ctx, l)
+
+	// Use a new port and let connections hang
+	cfg.Port = "8210"
+	cfg.ReadTimeout = 0
+	cfg.ReadHeaderTimeout = 0
+	cfg.ShutdownTimeout = time.Millisecond * 100
+
+	done := make(chan error)
+
+	go func() {
+		done <- server.StartServer(ctx, cfg, nil)
+	}()
+
+	// Wait for the server to start
+	time.Sleep(time.Millisecond * 50)
+
+	// Open a connection that never finishes its request
+	conn, err := net.Dial("tcp", cfg.GetAddr())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("GET /healthz HTTP/1.1\r\n")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("expected the server to close the connection after the shutdown timeout")
+	}
+}
+
+func TestStartServer_AdminAPI(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+	defer cancel()
+
+	cfg := config.NewConfig()
+	l := log.NewLogger(&strings.Builder{}, cfg)
+
+	ctx = log.WithLogger(ctx, l)
+
+	dir := t.TempDir()
+
+	cfg.Port = "8220"
+	cfg.AdminAddr = "localhost:8221"
+	cfg.AdminTokensFile = filepath.Join(dir, "tokens")
+	cfg.FingerPath = filepath.Join(dir, "fingers.yml")
+	cfg.URNPath = filepath.Join(dir, "urns.yml")
+	cfg.AuditFile = filepath.Join(dir, "audit.jsonl")
+
+	for path, content := range map[string]string{
+		cfg.AdminTokensFile: "ci: secret\n",
+		cfg.FingerPath:      "user@example.com:\n  name: User\n",
+		cfg.URNPath:         "",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("error writing %s: %v", path, err)
+		}
+	}
+
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		// Start the server
+		err := server.StartServer(ctx, cfg, nil)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}()
+
+	// Wait for the server to start
+	time.Sleep(time.Millisecond * 50)
+
+	do := func(method, url, token, body string) int {
+		r, _ := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	resources := "http://" + cfg.AdminAddr + "/admin/resources"
+	webfinger := "http://" + cfg.GetAddr() + "/.well-known/webfinger?resource="
+
+	// The files are served
+	if code := do(http.MethodGet, webfinger+"acct:user@example.com", "", ""); code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, code)
+	}
+
+	// The API needs a token
+	if code := do(http.MethodGet, resources, "", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, code)
+	}
+
+	// And isn't served on the main listener
+	if code := do(http.MethodGet, "http://"+cfg.GetAddr()+"/admin/resources", "secret", ""); code != http.StatusNotFound {
+		t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+	}
+
+	body := `{"subject":"bob@example.com","fields":{"name":"Bob"}}`
+	if code := do(http.MethodPost, resources, "secret", body); code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+	}
+
+	// New resources are served right away
+	if code := do(http.MethodGet, webfinger+"acct:bob@example.com", "", ""); code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, code)
+	}
+
+	// And audited with who made the change, once the server wrote it
+	cancel()
+	<-stopped
+
+	b, err := os.ReadFile(cfg.AuditFile)
+	if err != nil {
+		t.Fatalf("error reading audit file: %v", err)
+	}
+
+	var event audit.Event
+	if err := json.Unmarshal(b, &event); err != nil {
+		t.Fatalf("expected a single audit event, got %s", b)
+	}
+
+	if event.Actor != "ci" || len(event.Added) != 1 || event.Added[0].Subject != "acct:bob@example.com" {
+		t.Errorf("unexpected audit event %+v", event)
+	}
+}
@@ -0,0 +1,83 @@
+//go:build unix
+
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/internal/server"
+)
+
+// TestStartServer_Reload is not parallel since it sends SIGHUP to the
+// whole test process.
+func TestStartServer_Reload(t *testing.T) { //nolint:paralleltest // Signals affect every test
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+
+	cfg := config.NewConfig()
+	l := log.NewLogger(&strings.Builder{}, cfg)
+
+	ctx = log.WithLogger(ctx, l)
+
+	// Use a new port
+	cfg.Port = "8190"
+
+	// Start with no webfingers and add one to the file
+	cfg.FingerPath = filepath.Join(t.TempDir(), "fingers.yml")
+
+	if err := os.WriteFile(cfg.FingerPath, []byte("user@example.com:\n  name: John Doe\n"), 0o600); err != nil {
+		t.Fatalf("error writing fingers file: %v", err)
+	}
+
+	go func() {
+		// Start the server
+		err := server.StartServer(ctx, cfg, nil)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}()
+
+	// Wait for the server to start
+	time.Sleep(time.Millisecond * 50)
+
+	get := func() int {
+		r, _ := http.NewRequestWithContext(ctx,
+			http.MethodGet,
+			"http://"+cfg.GetAddr()+"/.well-known/webfinger?resource=acct:user@example.com",
+			http.NoBody,
+		)
+
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	if code := get(); code != http.StatusNotFound {
+		t.Fatalf("expected status code %d before reload, got %d", http.StatusNotFound, code)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("error sending SIGHUP: %v", err)
+	}
+
+	// Wait for the reload
+	time.Sleep(time.Millisecond * 50)
+
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected status code %d after reload, got %d", http.StatusOK, code)
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/log"
+)
+
+// CertReloadInterval is how often the certificate files are checked
+// for changes.
+const CertReloadInterval = 10 * time.Second
+
+// CertReloader serves a TLS certificate from disk and reloads it when
+// the certificate or key files change.
+type CertReloader struct {
+	certPath string
+	keyPath  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader loads the certificate and key from the given paths.
+func NewCertReloader(certPath, keyPath string) (*CertReloader, error) {
+	c := &CertReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+
+	if _, err := c.Reload(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetCertificate returns the current certificate. It can be used as
+// tls.Config.GetCertificate.
+func (c *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cert, nil
+}
+
+// Reload loads the certificate again if the files changed since the last
+// load, and reports whether it did.
+func (c *CertReloader) Reload() (bool, error) {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.RLock()
+	unchanged := c.cert != nil && modTime.Equal(c.modTime)
+	c.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return false, fmt.Errorf("error loading certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch checks the certificate files for changes every interval until
+// the context is done.
+func (c *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	l := log.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := c.Reload()
+			if err != nil {
+				// Keep serving the previous certificate
+				l.Error("Failed to reload TLS certificate", slog.Any("error", err))
+			} else if reloaded {
+				l.Info("Reloaded TLS certificate", slog.String("cert", c.certPath))
+			}
+		}
+	}
+}
+
+// latestModTime returns the most recent modification time of the
+// certificate and key files.
+func (c *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+
+	for _, path := range []string{c.certPath, c.keyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error reading certificate file: %w", err)
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// RedirectHandler redirects every request to the same URL over HTTPS on
+// the given port.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Trim(r.Host, "[]")
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			// Bracket IPv6 addresses
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
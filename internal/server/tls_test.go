@@ -0,0 +1,324 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/internal/server"
+)
+
+// writeCert writes a new self-signed certificate for localhost to the
+// given paths, with the given modification time.
+func writeCert(t *testing.T, certPath, keyPath string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("error generating serial: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshalling key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	if err := os.WriteFile(certPath, certPEM, 0o600); err != nil {
+		t.Fatalf("error writing certificate: %v", err)
+	}
+
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+
+	for _, path := range []string{certPath, keyPath} {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("error setting modification time: %v", err)
+		}
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("errors on missing files", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		_, err := server.NewCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("reloads changed certificates", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		certPath := filepath.Join(dir, "cert.pem")
+		keyPath := filepath.Join(dir, "key.pem")
+
+		writeCert(t, certPath, keyPath, time.Now().Add(-time.Hour))
+
+		c, err := server.NewCertReloader(certPath, keyPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		first, _ := c.GetCertificate(nil)
+		if first == nil {
+			t.Fatal("expected a certificate")
+		}
+
+		// Nothing changed
+		reloaded, err := c.Reload()
+		if err != nil || reloaded {
+			t.Errorf("expected no reload, got %v (%v)", reloaded, err)
+		}
+
+		// Replace the certificate
+		writeCert(t, certPath, keyPath, time.Now())
+
+		reloaded, err = c.Reload()
+		if err != nil || !reloaded {
+			t.Errorf("expected a reload, got %v (%v)", reloaded, err)
+		}
+
+		second, _ := c.GetCertificate(nil)
+		if second == first {
+			t.Error("expected the certificate to change")
+		}
+	})
+
+	t.Run("keeps the certificate on invalid files", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		certPath := filepath.Join(dir, "cert.pem")
+		keyPath := filepath.Join(dir, "key.pem")
+
+		writeCert(t, certPath, keyPath, time.Now().Add(-time.Hour))
+
+		c, err := server.NewCertReloader(certPath, keyPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		first, _ := c.GetCertificate(nil)
+
+		if err := os.WriteFile(certPath, []byte("invalid"), 0o600); err != nil {
+			t.Fatalf("error writing certificate: %v", err)
+		}
+
+		if _, err := c.Reload(); err == nil {
+			t.Error("expected error, got nil")
+		}
+
+		if got, _ := c.GetCertificate(nil); got != first {
+			t.Error("expected the certificate to be kept")
+		}
+	})
+
+	t.Run("watches for changes", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ctx = log.WithLogger(ctx, log.NewLogger(&strings.Builder{}, config.NewConfig()))
+
+		dir := t.TempDir()
+		certPath := filepath.Join(dir, "cert.pem")
+		keyPath := filepath.Join(dir, "key.pem")
+
+		writeCert(t, certPath, keyPath, time.Now().Add(-time.Hour))
+
+		c, err := server.NewCertReloader(certPath, keyPath)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		first, _ := c.GetCertificate(nil)
+
+		go c.Watch(ctx, time.Millisecond*10)
+
+		writeCert(t, certPath, keyPath, time.Now())
+
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if got, _ := c.GetCertificate(nil); got != first {
+				return
+			}
+
+			time.Sleep(time.Millisecond * 10)
+		}
+
+		t.Error("expected the certificate to be reloaded")
+	})
+}
+
+func TestRedirectHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		httpsPort string
+		url       string
+		want      string
+	}{
+		{
+			name:      "default https port",
+			httpsPort: "443",
+			url:       "http://example.com/.well-known/webfinger?resource=acct:user@example.com",
+			want:      "https://example.com/.well-known/webfinger?resource=acct:user@example.com",
+		},
+		{
+			name:      "custom https port",
+			httpsPort: "8443",
+			url:       "http://example.com:8080/healthz",
+			want:      "https://example.com:8443/healthz",
+		},
+		{
+			name:      "ipv6 host",
+			httpsPort: "443",
+			url:       "http://[::1]:8080/healthz",
+			want:      "https://[::1]/healthz",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, tc.url, http.NoBody)
+			w := httptest.NewRecorder()
+
+			server.RedirectHandler(tc.httpsPort).ServeHTTP(w, r)
+
+			if w.Code != http.StatusPermanentRedirect {
+				t.Errorf("expected status code %d, got %d", http.StatusPermanentRedirect, w.Code)
+			}
+
+			if got := w.Header().Get("Location"); got != tc.want {
+				t.Errorf("expected location %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStartServer_TLS(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+
+	cfg := config.NewConfig()
+	l := log.NewLogger(&strings.Builder{}, cfg)
+
+	ctx = log.WithLogger(ctx, l)
+
+	dir := t.TempDir()
+	cfg.TLSCert = filepath.Join(dir, "cert.pem")
+	cfg.TLSKey = filepath.Join(dir, "key.pem")
+
+	writeCert(t, cfg.TLSCert, cfg.TLSKey, time.Now())
+
+	// Use new ports
+	cfg.Port = "8180"
+	cfg.HTTPRedirectPort = "8181"
+
+	go func() {
+		// Start the server
+		err := server.StartServer(ctx, cfg, nil)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}()
+
+	// Wait for the server to start
+	time.Sleep(time.Millisecond * 50)
+
+	c := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // Self-signed test certificate
+		},
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	t.Run("serves https", func(t *testing.T) {
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+cfg.GetAddr()+"/healthz", http.NoBody)
+
+		resp, err := c.Do(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+	})
+
+	t.Run("redirects http", func(t *testing.T) {
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+cfg.GetRedirectAddr()+"/healthz", http.NoBody)
+
+		resp, err := c.Do(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusPermanentRedirect {
+			t.Errorf("expected status code %d, got %d", http.StatusPermanentRedirect, resp.StatusCode)
+		}
+
+		if want := "https://" + cfg.GetAddr() + "/healthz"; resp.Header.Get("Location") != want {
+			t.Errorf("expected location %s, got %s", want, resp.Header.Get("Location"))
+		}
+	})
+}
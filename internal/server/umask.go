@@ -0,0 +1,17 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// withUmask runs fn with a umask that creates files with at most the mode,
+// so they never have looser permissions before being chmodded.
+func withUmask(mode os.FileMode, fn func() error) error {
+	old := syscall.Umask(int(os.ModePerm &^ mode))
+	defer syscall.Umask(old)
+
+	return fn()
+}
@@ -0,0 +1,10 @@
+package server
+
+import (
+	"os"
+)
+
+// withUmask runs fn as is on Windows, which has no umask.
+func withUmask(_ os.FileMode, fn func() error) error {
+	return fn()
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"git.maronato.dev/maronato/finger/handler"
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/middleware"
+	"git.maronato.dev/maronato/finger/internal/privacy"
+	"git.maronato.dev/maronato/finger/internal/store"
+)
+
+// WebfingerHandler serves the webfingers in the store, switching to the
+// new ones whenever they are reloaded. The options are added to the ones
+// derived from the config.
+func WebfingerHandler(cfg *config.Config, s *store.Store, opts ...handler.Option) (http.Handler, error) {
+	return newWebfingerHandler(cfg, s, newLookupRedactor(cfg), opts...)
+}
+
+// newLookupRedactor creates the redactor that hides looked up resources
+// and rels as configured.
+func newLookupRedactor(cfg *config.Config) *privacy.Redactor {
+	return privacy.NewRedactor(privacy.Mode(cfg.LogLookups), cfg.LogLookupsKey)
+}
+
+// newWebfingerHandler is WebfingerHandler with the redactor of the lookup
+// logs, so it can be shared with the analytics.
+func newWebfingerHandler(cfg *config.Config, s *store.Store, redactor *privacy.Redactor, opts ...handler.Option) (http.Handler, error) {
+	// Configure the webfinger response caching
+	resourceMaxAges, err := cfg.GetResourceMaxAges()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing resource max ages: %w", err)
+	}
+
+	handlerOpts := []handler.Option{
+		handler.WithMaxAge(cfg.CacheMaxAge),
+	}
+	if cfg.Compress {
+		handlerOpts = append(handlerOpts, handler.WithGzip(max(cfg.CompressMinSize, 1)))
+	}
+
+	for resource, maxAge := range resourceMaxAges {
+		handlerOpts = append(handlerOpts, handler.WithResourceMaxAge(resource, maxAge))
+	}
+
+	// Log the lookups, hiding them as configured
+	handlerOpts = append(handlerOpts, handler.WithObserver(LogLookups(redactor)))
+
+	handlerOpts = append(handlerOpts, opts...)
+
+	// Build a new handler every time the webfingers change
+	current := &atomic.Pointer[http.Handler]{}
+
+	s.OnUpdate(func(snap *store.Snapshot) {
+		opts := append([]handler.Option{handler.WithLastModified(snap.LoadedAt)}, handlerOpts...)
+		h := handler.WebfingerHandler(snap.Fingers, opts...)
+
+		current.Store(&h)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*current.Load()).ServeHTTP(w, r)
+	}), nil
+}
+
+// LogLookups adds the resource, rels and outcome of every lookup to the
+// request log. The resource and rels go through the redactor first.
+func LogLookups(redactor *privacy.Redactor) handler.Observer {
+	return func(r *http.Request, lookup handler.Lookup) {
+		attrs := make([]any, 0, 3) //nolint:gomnd // Resource, rels and outcome
+
+		if resource, ok := redactor.Redact(lookup.Resource); ok {
+			attrs = append(attrs, slog.String("resource", resource))
+		}
+
+		if len(lookup.Rels) > 0 {
+			rels := make([]string, 0, len(lookup.Rels))
+
+			for _, rel := range lookup.Rels {
+				if v, ok := redactor.Redact(rel); ok {
+					rels = append(rels, v)
+				}
+			}
+
+			if len(rels) > 0 {
+				attrs = append(attrs, slog.Any("rel", rels))
+			}
+		}
+
+		attrs = append(attrs, slog.String("outcome", string(lookup.Outcome)))
+
+		middleware.AddLogAttrs(r.Context(), slog.Group("lookup", attrs...))
+	}
+}
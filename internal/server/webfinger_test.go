@@ -0,0 +1,143 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/config"
+	"git.maronato.dev/maronato/finger/internal/log"
+	"git.maronato.dev/maronato/finger/internal/middleware"
+	"git.maronato.dev/maronato/finger/internal/server"
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+func TestWebfingerHandler(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewConfig()
+	s := store.New(nil, nil)
+
+	h, err := server.WebfingerHandler(cfg, s)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	get := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:user@example.com", http.NoBody)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusNotFound {
+		t.Fatalf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// Swap in new webfingers
+	s.Set(context.Background(), webfingers.WebFingers{
+		"acct:user@example.com": {Subject: "acct:user@example.com"},
+	})
+
+	w := get()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Last-Modified follows the load time
+	if want := s.Current().LoadedAt.UTC().Format(http.TimeFormat); w.Header().Get("Last-Modified") != want {
+		t.Errorf("expected Last-Modified %s, got %s", want, w.Header().Get("Last-Modified"))
+	}
+}
+
+func TestWebfingerHandler_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewConfig()
+	cfg.CacheResourceMaxAge = []string{"invalid"}
+
+	if _, err := server.WebfingerHandler(cfg, store.New(nil, nil)); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestWebfingerHandler_LogLookups(t *testing.T) {
+	t.Parallel()
+
+	fingers := webfingers.WebFingers{
+		"acct:user@example.com": {Subject: "acct:user@example.com"},
+	}
+
+	tests := []struct {
+		name      string
+		mode      string
+		query     string
+		want      []string
+		wantNotIn []string
+	}{
+		{
+			name:  "plain",
+			mode:  config.LogLookupsPlain,
+			query: "resource=acct:user@example.com&rel=avatar",
+			want:  []string{`"lookup":{"resource":"acct:user@example.com","rel":["avatar"],"outcome":"hit"}`},
+		},
+		{
+			name:  "miss",
+			mode:  config.LogLookupsPlain,
+			query: "resource=acct:other@example.com",
+			want:  []string{`"lookup":{"resource":"acct:other@example.com","outcome":"miss"}`},
+		},
+		{
+			name:      "hash",
+			mode:      config.LogLookupsHash,
+			query:     "resource=acct:user@example.com&rel=avatar",
+			want:      []string{`"lookup":{"resource":"`, `"outcome":"hit"`},
+			wantNotIn: []string{"acct:user@example.com", "avatar"},
+		},
+		{
+			name:      "none",
+			mode:      config.LogLookupsNone,
+			query:     "resource=acct:user@example.com&rel=avatar",
+			want:      []string{`"lookup":{"outcome":"hit"}`},
+			wantNotIn: []string{"acct:user@example.com", "avatar"},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.NewConfig()
+			cfg.LogLookups = tc.mode
+
+			stdout := &strings.Builder{}
+			ctx := log.WithLogger(context.Background(), log.NewLogger(stdout, cfg))
+
+			h, err := server.WebfingerHandler(cfg, store.New(fingers, nil))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/.well-known/webfinger?"+tc.query, http.NoBody)
+			middleware.RequestLogger(h).ServeHTTP(httptest.NewRecorder(), r)
+
+			for _, want := range tc.want {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("expected %s in the logs, got %s", want, stdout.String())
+				}
+			}
+
+			for _, notWant := range tc.wantNotIn {
+				if strings.Contains(stdout.String(), notWant) {
+					t.Errorf("expected no %s in the logs, got %s", notWant, stdout.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+// LoadFunc loads a fresh copy of the webfingers from their source.
+type LoadFunc func(ctx context.Context) (webfingers.WebFingers, error)
+
+// Snapshot is an immutable version of the webfingers being served.
+type Snapshot struct {
+	Fingers  webfingers.WebFingers
+	LoadedAt time.Time
+}
+
+// Store holds the webfingers being served and replaces them atomically
+// when they are reloaded.
+type Store struct {
+	load LoadFunc
+
+	// swap serializes making new webfingers with swapping them in, so an
+	// update can't be overwritten by webfingers made before it.
+	swap sync.Mutex
+
+	// mu serializes updates so hooks see them in order.
+	mu       sync.Mutex
+	current  atomic.Pointer[Snapshot]
+	onUpdate []func(*Snapshot)
+	onChange []func(ctx context.Context, prev, next *Snapshot)
+	onReload []func(error)
+}
+
+// New creates a store serving the given webfingers, which are reloaded
+// with load. A nil load function makes Reload a no-op.
+func New(fingers webfingers.WebFingers, load LoadFunc) *Store {
+	s := &Store{load: load}
+
+	s.current.Store(&Snapshot{
+		Fingers:  fingers,
+		LoadedAt: time.Now(),
+	})
+
+	return s
+}
+
+// Current returns the webfingers currently being served.
+func (s *Store) Current() *Snapshot {
+	return s.current.Load()
+}
+
+// OnUpdate registers a function that is called with the current snapshot
+// right away and then every time it changes.
+func (s *Store) OnUpdate(fn func(*Snapshot)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onUpdate = append(s.onUpdate, fn)
+
+	fn(s.current.Load())
+}
+
+// OnChange registers a function that is called every time the webfingers
+// are replaced, with the context of the change and the snapshots before
+// and after it.
+func (s *Store) OnChange(fn func(ctx context.Context, prev, next *Snapshot)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onChange = append(s.onChange, fn)
+}
+
+// OnReload registers a function that is called after every reload with
+// its error, if any.
+func (s *Store) OnReload(fn func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onReload = append(s.onReload, fn)
+}
+
+// Reload loads the webfingers again and swaps them in. The current
+// webfingers are kept if loading fails.
+func (s *Store) Reload(ctx context.Context) error {
+	if s.load == nil {
+		return nil
+	}
+
+	err := s.Update(ctx, func() (webfingers.WebFingers, error) {
+		fingers, err := s.load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error loading webfingers: %w", err)
+		}
+
+		return fingers, nil
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, fn := range s.onReload {
+		fn(err)
+	}
+
+	return err
+}
+
+// Update swaps in the webfingers made by fn, unless it fails. Reloads and
+// other updates wait until it's done.
+func (s *Store) Update(ctx context.Context, fn func() (webfingers.WebFingers, error)) error {
+	s.swap.Lock()
+	defer s.swap.Unlock()
+
+	fingers, err := fn()
+	if err != nil {
+		return err
+	}
+
+	s.set(ctx, fingers)
+
+	return nil
+}
+
+// Set replaces the webfingers being served.
+func (s *Store) Set(ctx context.Context, fingers webfingers.WebFingers) {
+	s.swap.Lock()
+	defer s.swap.Unlock()
+
+	s.set(ctx, fingers)
+}
+
+func (s *Store) set(ctx context.Context, fingers webfingers.WebFingers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := &Snapshot{
+		Fingers:  fingers,
+		LoadedAt: time.Now(),
+	}
+
+	prev := s.current.Swap(snap)
+
+	for _, fn := range s.onUpdate {
+		fn(snap)
+	}
+
+	for _, fn := range s.onChange {
+		fn(ctx, prev, snap)
+	}
+}
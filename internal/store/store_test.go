@@ -0,0 +1,158 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/store"
+	"git.maronato.dev/maronato/finger/webfingers"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	initial := webfingers.WebFingers{
+		"acct:user@example.com": {Subject: "acct:user@example.com"},
+	}
+	reloaded := webfingers.WebFingers{
+		"acct:other@example.com": {Subject: "acct:other@example.com"},
+	}
+
+	errLoad := errors.New("load failed")
+
+	t.Run("serves the initial webfingers", func(t *testing.T) {
+		t.Parallel()
+
+		s := store.New(initial, nil)
+
+		if len(s.Current().Fingers) != 1 || s.Current().Fingers["acct:user@example.com"] == nil {
+			t.Errorf("expected the initial webfingers, got %v", s.Current().Fingers)
+		}
+
+		if s.Current().LoadedAt.IsZero() {
+			t.Error("expected the load time to be set")
+		}
+	})
+
+	t.Run("reload without a load function is a no-op", func(t *testing.T) {
+		t.Parallel()
+
+		s := store.New(initial, nil)
+		before := s.Current()
+
+		if err := s.Reload(context.Background()); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if s.Current() != before {
+			t.Error("expected the snapshot to be kept")
+		}
+	})
+
+	t.Run("reloads webfingers", func(t *testing.T) {
+		t.Parallel()
+
+		s := store.New(initial, func(_ context.Context) (webfingers.WebFingers, error) {
+			return reloaded, nil
+		})
+
+		updates := []*store.Snapshot{}
+		s.OnUpdate(func(snap *store.Snapshot) {
+			updates = append(updates, snap)
+		})
+
+		reloads := []error{}
+		s.OnReload(func(err error) {
+			reloads = append(reloads, err)
+		})
+
+		if err := s.Reload(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(reloads) != 1 || reloads[0] != nil {
+			t.Errorf("expected one successful reload, got %v", reloads)
+		}
+
+		if s.Current().Fingers["acct:other@example.com"] == nil {
+			t.Errorf("expected the reloaded webfingers, got %v", s.Current().Fingers)
+		}
+
+		if len(updates) != 2 || updates[1] != s.Current() {
+			t.Errorf("expected hooks to receive the initial and reloaded snapshots, got %d updates", len(updates))
+		}
+	})
+
+	t.Run("reports changes with their context", func(t *testing.T) {
+		t.Parallel()
+
+		type key struct{}
+
+		s := store.New(initial, nil)
+		before := s.Current()
+
+		var (
+			gotCtx        context.Context
+			gotPrev, next *store.Snapshot
+		)
+
+		s.OnChange(func(ctx context.Context, prev, n *store.Snapshot) {
+			gotCtx, gotPrev, next = ctx, prev, n
+		})
+
+		s.Set(context.WithValue(context.Background(), key{}, "admin"), reloaded)
+
+		if gotCtx == nil || gotCtx.Value(key{}) != "admin" {
+			t.Error("expected the hook to receive the context of the change")
+		}
+
+		if gotPrev != before || next != s.Current() {
+			t.Error("expected the hook to receive the previous and new snapshots")
+		}
+	})
+
+	t.Run("keeps webfingers on failed updates", func(t *testing.T) {
+		t.Parallel()
+
+		s := store.New(initial, nil)
+		before := s.Current()
+
+		err := s.Update(context.Background(), func() (webfingers.WebFingers, error) {
+			return nil, errLoad
+		})
+		if !errors.Is(err, errLoad) {
+			t.Errorf("expected update error, got %v", err)
+		}
+
+		if s.Current() != before {
+			t.Error("expected the snapshot to be kept")
+		}
+	})
+
+	t.Run("keeps webfingers on failed reloads", func(t *testing.T) {
+		t.Parallel()
+
+		s := store.New(initial, func(_ context.Context) (webfingers.WebFingers, error) {
+			return nil, errLoad
+		})
+		before := s.Current()
+
+		reloads := []error{}
+		s.OnReload(func(err error) {
+			reloads = append(reloads, err)
+		})
+
+		if err := s.Reload(context.Background()); !errors.Is(err, errLoad) {
+			t.Errorf("expected load error, got %v", err)
+		}
+
+		if len(reloads) != 1 || !errors.Is(reloads[0], errLoad) {
+			t.Errorf("expected one failed reload, got %v", reloads)
+		}
+
+		if s.Current() != before {
+			t.Error("expected the snapshot to be kept")
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package systemd
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// clockMonotonic is CLOCK_MONOTONIC, the clock systemd expects.
+const clockMonotonic = 1
+
+// monotonicUsec returns the CLOCK_MONOTONIC time in microseconds.
+func monotonicUsec() (int64, bool) {
+	var ts syscall.Timespec
+
+	_, _, errno := syscall.RawSyscall(syscall.SYS_CLOCK_GETTIME, clockMonotonic, uintptr(unsafe.Pointer(&ts)), 0)
+	if errno != 0 {
+		return 0, false
+	}
+
+	return ts.Nano() / 1000, true //nolint:gomnd // Nanoseconds to microseconds
+}
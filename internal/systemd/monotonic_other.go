@@ -0,0 +1,8 @@
+//go:build !linux
+
+package systemd
+
+// monotonicUsec is unavailable outside of Linux, where systemd doesn't run.
+func monotonicUsec() (int64, bool) {
+	return 0, false
+}
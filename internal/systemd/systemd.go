@@ -0,0 +1,131 @@
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Ready tells systemd the service finished starting up.
+	Ready = "READY=1"
+	// Stopping tells systemd the service is shutting down.
+	Stopping = "STOPPING=1"
+	// Watchdog keeps the service watchdog from firing.
+	Watchdog = "WATCHDOG=1"
+
+	// listenFdsStart is the first file descriptor passed by systemd.
+	listenFdsStart = 3
+)
+
+// ErrInvalidEnv is returned when the systemd environment variables are invalid.
+var ErrInvalidEnv = errors.New("invalid systemd environment")
+
+// Listeners returns the sockets passed by systemd socket activation, in
+// the order they are declared in the socket unit. It returns no listeners
+// if the process was not socket activated.
+func Listeners() ([]net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+
+	// The sockets are only meant for the process systemd started
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%w: LISTEN_FDS=%q", ErrInvalidEnv, fds)
+	}
+
+	// Don't pass the sockets on to child processes
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, n)
+
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+
+		l, err := net.FileListener(f)
+		if err != nil {
+			return nil, fmt.Errorf("error using systemd socket %d: %w", fd, err)
+		}
+
+		// FileListener dups the descriptor, so the original can be closed
+		// and won't leak into child processes
+		f.Close()
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// Notify sends a state message to systemd. It reports whether the message
+// was sent, which is false when the service was not started by systemd.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// Abstract sockets start with a null byte
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("error connecting to systemd: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("error notifying systemd: %w", err)
+	}
+
+	return true, nil
+}
+
+// Reloading returns the message telling systemd the service is reloading
+// its configuration. It must be followed by Ready once the reload is done.
+// It includes the MONOTONIC_USEC that Type=notify-reload requires, where
+// the clock is available.
+func Reloading() string {
+	usec, ok := monotonicUsec()
+	if !ok {
+		return "RELOADING=1"
+	}
+
+	return "RELOADING=1\nMONOTONIC_USEC=" + strconv.FormatInt(usec, 10)
+}
+
+// WatchdogInterval returns how often the service must notify the watchdog,
+// which is half the timeout configured in the unit. It returns zero if the
+// watchdog is disabled.
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+
+	// The watchdog may be meant for another process
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: WATCHDOG_USEC=%q", ErrInvalidEnv, usec)
+	}
+
+	return time.Duration(n) * time.Microsecond / 2, nil //nolint:gomnd // Notify twice per timeout
+}
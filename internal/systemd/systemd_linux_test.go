@@ -0,0 +1,34 @@
+package systemd_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/systemd"
+)
+
+func TestReloading(t *testing.T) {
+	t.Parallel()
+
+	usec := func() int64 {
+		t.Helper()
+
+		state, value, ok := strings.Cut(systemd.Reloading(), "\nMONOTONIC_USEC=")
+		if !ok || state != "RELOADING=1" {
+			t.Fatalf("unexpected message %q", systemd.Reloading())
+		}
+
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			t.Fatalf("invalid MONOTONIC_USEC %q", value)
+		}
+
+		return n
+	}
+
+	// The clock never goes back
+	if first, second := usec(), usec(); second < first {
+		t.Errorf("MONOTONIC_USEC went from %d to %d", first, second)
+	}
+}
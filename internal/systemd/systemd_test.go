@@ -0,0 +1,161 @@
+package systemd_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"git.maronato.dev/maronato/finger/internal/systemd"
+)
+
+func TestListeners(t *testing.T) {
+	t.Run("returns nothing without activation", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "")
+		t.Setenv("LISTEN_FDS", "")
+
+		listeners, err := systemd.Listeners()
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if len(listeners) != 0 {
+			t.Errorf("expected no listeners, got %d", len(listeners))
+		}
+	})
+
+	t.Run("ignores sockets for other processes", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+		t.Setenv("LISTEN_FDS", "1")
+
+		listeners, err := systemd.Listeners()
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if len(listeners) != 0 {
+			t.Errorf("expected no listeners, got %d", len(listeners))
+		}
+	})
+
+	t.Run("errors on invalid fds", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		t.Setenv("LISTEN_FDS", "invalid")
+
+		if _, err := systemd.Listeners(); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestNotify(t *testing.T) {
+	t.Run("does nothing without systemd", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+
+		sent, err := systemd.Notify(systemd.Ready)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if sent {
+			t.Error("expected the message not to be sent")
+		}
+	})
+
+	t.Run("sends messages", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "notify.sock")
+
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+		if err != nil {
+			t.Fatalf("error creating socket: %v", err)
+		}
+		defer conn.Close()
+
+		t.Setenv("NOTIFY_SOCKET", socket)
+
+		sent, err := systemd.Notify(systemd.Ready)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !sent {
+			t.Error("expected the message to be sent")
+		}
+
+		buf := make([]byte, 64)
+
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("error reading message: %v", err)
+		}
+
+		if got := string(buf[:n]); got != systemd.Ready {
+			t.Errorf("expected message %q, got %q", systemd.Ready, got)
+		}
+	})
+
+	t.Run("errors on missing socket", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing.sock"))
+
+		if _, err := systemd.Notify(systemd.Ready); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		usec    string
+		pid     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			name: "disabled",
+			want: 0,
+		},
+		{
+			name: "half the timeout",
+			usec: "10000000",
+			want: 5 * time.Second,
+		},
+		{
+			name: "matching pid",
+			usec: "10000000",
+			pid:  strconv.Itoa(os.Getpid()),
+			want: 5 * time.Second,
+		},
+		{
+			name: "other pid",
+			usec: "10000000",
+			pid:  strconv.Itoa(os.Getpid() + 1),
+			want: 0,
+		},
+		{
+			name:    "invalid",
+			usec:    "invalid",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tc.usec)
+			t.Setenv("WATCHDOG_PID", tc.pid)
+
+			got, err := systemd.WatchdogInterval()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("WatchdogInterval() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("WatchdogInterval() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
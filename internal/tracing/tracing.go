@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxRequestIDLength is the maximum length of request IDs accepted from
+// clients.
+const maxRequestIDLength = 128
+
+// ErrInvalidTraceParent is returned when a traceparent header is invalid.
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+type traceParentCtxKey struct{}
+
+type requestIDCtxKey struct{}
+
+// TraceParent is a W3C trace context traceparent header.
+type TraceParent struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// NewTraceParent starts a new trace.
+func NewTraceParent() TraceParent {
+	var tp TraceParent
+
+	_, _ = rand.Read(tp.TraceID[:])
+	_, _ = rand.Read(tp.SpanID[:])
+
+	return tp
+}
+
+// ParseTraceParent parses a version 00 traceparent header. Headers of
+// later versions are parsed as version 00, as the specification asks.
+func ParseTraceParent(s string) (TraceParent, error) {
+	var tp TraceParent
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 {
+		return tp, fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return tp, fmt.Errorf("%w: unsupported version in %q", ErrInvalidTraceParent, s)
+	}
+
+	var flags [1]byte
+
+	for _, f := range []struct {
+		dst []byte
+		src string
+	}{
+		{tp.TraceID[:], parts[1]},
+		{tp.SpanID[:], parts[2]},
+		{flags[:], parts[3]},
+	} {
+		// Only lowercase hex is valid
+		if len(f.src) != hex.EncodedLen(len(f.dst)) || strings.ToLower(f.src) != f.src {
+			return tp, fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+		}
+
+		if _, err := hex.Decode(f.dst, []byte(f.src)); err != nil {
+			return tp, fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+		}
+	}
+
+	if tp.TraceID == [16]byte{} || tp.SpanID == [8]byte{} {
+		return tp, fmt.Errorf("%w: all zero IDs in %q", ErrInvalidTraceParent, s)
+	}
+
+	tp.Flags = flags[0]
+
+	return tp, nil
+}
+
+// Child returns the traceparent of a new span in the same trace.
+func (tp TraceParent) Child() TraceParent {
+	child := tp
+	_, _ = rand.Read(child.SpanID[:])
+
+	return child
+}
+
+// TraceIDString returns the trace ID in hex.
+func (tp TraceParent) TraceIDString() string {
+	return hex.EncodeToString(tp.TraceID[:])
+}
+
+// SpanIDString returns the span ID in hex.
+func (tp TraceParent) SpanIDString() string {
+	return hex.EncodeToString(tp.SpanID[:])
+}
+
+// String formats the traceparent as a version 00 header.
+func (tp TraceParent) String() string {
+	return "00-" + tp.TraceIDString() + "-" + tp.SpanIDString() + "-" + hex.EncodeToString([]byte{tp.Flags})
+}
+
+// NewRequestID generates a random request ID.
+func NewRequestID() string {
+	var b [16]byte
+
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
+
+// ValidRequestID reports whether a request ID sent by a client can be
+// used. It must be short and made of printable ASCII without spaces, so it
+// can't be used to forge log lines or headers.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithTraceParent returns a copy of ctx carrying the traceparent of the
+// current span, so it can be propagated to outgoing requests.
+func WithTraceParent(ctx context.Context, tp TraceParent) context.Context {
+	return context.WithValue(ctx, traceParentCtxKey{}, tp)
+}
+
+// TraceParentFromContext returns the traceparent stored in ctx, if any.
+func TraceParentFromContext(ctx context.Context) (TraceParent, bool) {
+	tp, ok := ctx.Value(traceParentCtxKey{}).(TraceParent)
+
+	return tp, ok
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDCtxKey{}).(string)
+
+	return id, ok
+}
@@ -0,0 +1,143 @@
+package tracing_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"git.maronato.dev/maronato/finger/internal/tracing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			want:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:   "future version with extra fields",
+			header: "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra",
+			want:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		},
+		{
+			name:    "version 00 with extra fields",
+			header:  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantErr: true,
+		},
+		{
+			name:    "invalid version",
+			header:  "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		{
+			name:    "uppercase",
+			header:  "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		{
+			name:    "zero trace id",
+			header:  "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		{
+			name:    "zero span id",
+			header:  "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			wantErr: true,
+		},
+		{
+			name:    "short trace id",
+			header:  "00-4bf92f3577b34da6-00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		{
+			name:    "not hex",
+			header:  "00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			header:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tracing.ParseTraceParent(tc.header)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseTraceParent() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if err != nil {
+				if !errors.Is(err, tracing.ErrInvalidTraceParent) {
+					t.Errorf("expected ErrInvalidTraceParent, got %v", err)
+				}
+
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("ParseTraceParent() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTraceParent_Child(t *testing.T) {
+	t.Parallel()
+
+	parent := tracing.NewTraceParent()
+	child := parent.Child()
+
+	if child.TraceID != parent.TraceID {
+		t.Error("expected the child to keep the trace ID")
+	}
+
+	if child.SpanID == parent.SpanID {
+		t.Error("expected the child to have a new span ID")
+	}
+
+	if _, err := tracing.ParseTraceParent(child.String()); err != nil {
+		t.Errorf("expected the child to be valid, got %v", err)
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "uuid", id: "3b241101-e2bb-4255-8caf-4136c566a962", want: true},
+		{name: "generated", id: tracing.NewRequestID(), want: true},
+		{name: "empty", id: "", want: false},
+		{name: "space", id: "a b", want: false},
+		{name: "newline", id: "a\nb", want: false},
+		{name: "non ascii", id: "ação", want: false},
+		{name: "too long", id: strings.Repeat("a", 129), want: false},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tracing.ValidRequestID(tc.id); got != tc.want {
+				t.Errorf("ValidRequestID(%q) = %v, want %v", tc.id, got, tc.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -13,6 +14,16 @@ var version = "dev"
 func main() {
 	// Run the server
 	if err := cmd.Run(version); err != nil {
+		// Some commands report their result with the exit code
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.Err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+			}
+
+			os.Exit(exitErr.Code)
+		}
+
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
@@ -22,6 +22,27 @@ type WebFinger struct {
 // Resources is a simplified webfinger map.
 type Resources map[string]map[string]string
 
+// Find returns the key of the resource with the same subject as resource,
+// so acct:user@example.com finds user@example.com.
+func (r Resources) Find(resource string) (string, bool) {
+	if _, ok := r[resource]; ok {
+		return resource, true
+	}
+
+	subject, err := ParseSubject(resource)
+	if err != nil {
+		return "", false
+	}
+
+	for k := range r {
+		if s, err := ParseSubject(k); err == nil && s == subject {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
 // URNAliases is a map of URN aliases.
 type URNAliases map[string]string
 
@@ -39,21 +60,9 @@ func NewWebFingers(resources Resources, urnAliases URNAliases) (WebFingers, erro
 
 	// Parse the resources.
 	for k, v := range resources {
-		subject := k
-
-		// Remove leading acct: if present.
-		if len(k) > 5 && subject[:5] == "acct:" {
-			subject = subject[5:]
-		}
-
-		// The subject must be a URL or email address.
-		if _, err := mail.ParseAddress(subject); err != nil {
-			if _, err := url.ParseRequestURI(subject); err != nil {
-				return nil, fmt.Errorf("error parsing resource subject (%s): %w", k, err)
-			}
-		} else {
-			// Add acct: back to the subject if it is an email address.
-			subject = fmt.Sprintf("acct:%s", subject)
+		subject, err := ParseSubject(k)
+		if err != nil {
+			return nil, err
 		}
 
 		// Create a new webfinger.
@@ -92,3 +101,26 @@ func NewWebFingers(resources Resources, urnAliases URNAliases) (WebFingers, erro
 
 	return fingers, nil
 }
+
+// ParseSubject returns the subject of a resource, which must be a URL or an
+// email address. Email addresses get the acct: scheme.
+func ParseSubject(resource string) (string, error) {
+	subject := resource
+
+	// Remove leading acct: if present.
+	if len(resource) > 5 && subject[:5] == "acct:" {
+		subject = subject[5:]
+	}
+
+	// The subject must be a URL or email address.
+	if _, err := mail.ParseAddress(subject); err != nil {
+		if _, err := url.ParseRequestURI(subject); err != nil {
+			return "", fmt.Errorf("error parsing resource subject (%s): %w", resource, err)
+		}
+	} else {
+		// Add acct: back to the subject if it is an email address.
+		subject = fmt.Sprintf("acct:%s", subject)
+	}
+
+	return subject, nil
+}
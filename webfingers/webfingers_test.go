@@ -229,3 +229,87 @@ func TestNewWebFingers(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSubject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		resource string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "email",
+			resource: "user@example.com",
+			want:     "acct:user@example.com",
+		},
+		{
+			name:     "acct",
+			resource: "acct:user@example.com",
+			want:     "acct:user@example.com",
+		},
+		{
+			name:     "url",
+			resource: "https://example.com/user",
+			want:     "https://example.com/user",
+		},
+		{
+			name:     "invalid",
+			resource: "not a subject",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := webfingers.ParseSubject(tc.resource)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSubject() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("ParseSubject() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResources_Find(t *testing.T) {
+	t.Parallel()
+
+	resources := webfingers.Resources{
+		"user@example.com":         {},
+		"https://example.com/user": {},
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		want     string
+		wantOk   bool
+	}{
+		{"same key", "user@example.com", "user@example.com", true},
+		{"with acct", "acct:user@example.com", "user@example.com", true},
+		{"url", "https://example.com/user", "https://example.com/user", true},
+		{"missing", "acct:other@example.com", "", false},
+		{"invalid", "not a subject", "", false},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := resources.Find(tc.resource)
+			if got != tc.want || ok != tc.wantOk {
+				t.Errorf("Find() = %v, %v, want %v, %v", got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}